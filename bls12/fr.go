@@ -0,0 +1,250 @@
+package bls12
+
+import "errors"
+
+// Fr is an element of the scalar field, the integers modulo the order r of
+// G1 and G2. The zero value is zero.
+//
+// Unlike math/big, all operations run in time that depends only on the
+// length of their inputs, and don't allocate, so that Fr can hold secrets.
+type Fr struct {
+	// l are the little-endian 64-bit limbs of x * 2^256 mod r, the
+	// Montgomery representation of x.
+	l [4]uint64
+}
+
+// frModulus is r, in little-endian limbs.
+var frModulus = [4]uint64{0xffffffff00000001, 0x53bda402fffe5bfe, 0x3339d80809a1d805, 0x73eda753299d7d48}
+
+// frR2 is 2^512 mod r, which moves a value into Montgomery representation.
+var frR2 = [4]uint64{0xc999e990f3f29c6d, 0x2b6cedcb87925c23, 0x05d314967254398f, 0x0748d9d99f59ff11}
+
+// frOne is 2^256 mod r, the Montgomery representation of 1.
+var frOne = [4]uint64{0x00000001fffffffe, 0x5884b7fa00034802, 0x998c4fefecbc4ff5, 0x1824b159acc5056f}
+
+// frInv is -1/r mod 2^64.
+const frInv = 0xfffffffeffffffff
+
+// frModulusMinusTwo is r - 2, big-endian, the exponent of the inverse.
+var frModulusMinusTwo = []byte{
+	0x73, 0xed, 0xa7, 0x53, 0x29, 0x9d, 0x7d, 0x48, 0x33, 0x39, 0xd8, 0x08, 0x09, 0xa1, 0xd8, 0x05,
+	0x53, 0xbd, 0xa4, 0x02, 0xff, 0xfe, 0x5b, 0xfe, 0xff, 0xff, 0xff, 0xfe, 0xff, 0xff, 0xff, 0xff,
+}
+
+func (fr *Fr) SetZero() *Fr {
+	fr.l = [4]uint64{}
+	return fr
+}
+
+func (fr *Fr) SetOne() *Fr {
+	fr.l = frOne
+	return fr
+}
+
+func (fr *Fr) Set(a *Fr) *Fr {
+	fr.l = a.l
+	return fr
+}
+
+func (fr *Fr) Copy() *Fr {
+	return &Fr{l: fr.l}
+}
+
+// SetBytes sets fr to the big-endian integer s, which must be at most 32
+// bytes long and less than r, like the scalars IsScalar accepts.
+func (fr *Fr) SetBytes(s []byte) (*Fr, error) {
+	if len(s) > 32 {
+		return nil, errors.New("bls12: scalar too long")
+	}
+	var x [4]uint64
+	for i, b := range s {
+		j := len(s) - 1 - i
+		x[j/8] |= uint64(b) << (8 * uint(j%8))
+	}
+	// x < r if subtracting r borrows.
+	var borrow uint64
+	for i := range x {
+		_, borrow = sub64(x[i], frModulus[i], borrow)
+	}
+	if borrow == 0 {
+		return nil, errors.New("bls12: scalar not reduced")
+	}
+	frMul(&fr.l, &x, &frR2)
+	return fr, nil
+}
+
+// Bytes writes fr to buf as a 32-byte big-endian integer, the format
+// ScalarMult takes, and returns buf[:]. Using a caller-provided buffer
+// makes it possible to zero the encoding of secrets once done.
+func (fr *Fr) Bytes(buf *[32]byte) []byte {
+	var x [4]uint64
+	frMul(&x, &fr.l, &[4]uint64{1})
+	for i := range buf {
+		j := len(buf) - 1 - i
+		buf[i] = byte(x[j/8] >> (8 * uint(j%8)))
+	}
+	return buf[:]
+}
+
+// Add sets fr to fr + a.
+func (fr *Fr) Add(a *Fr) *Fr {
+	var carry uint64
+	var t [4]uint64
+	for i := range t {
+		t[i], carry = add64(fr.l[i], a.l[i], carry)
+	}
+	// r < 2^255, so the sum fits in four limbs.
+	frReduce(&fr.l, &t, 0)
+	return fr
+}
+
+// Sub sets fr to fr - a.
+func (fr *Fr) Sub(a *Fr) *Fr {
+	var borrow uint64
+	for i := range fr.l {
+		fr.l[i], borrow = sub64(fr.l[i], a.l[i], borrow)
+	}
+	// If it borrowed, add r back.
+	mask := -borrow
+	var carry uint64
+	for i := range fr.l {
+		fr.l[i], carry = add64(fr.l[i], frModulus[i]&mask, carry)
+	}
+	return fr
+}
+
+// Mul sets fr to fr * a.
+func (fr *Fr) Mul(a *Fr) *Fr {
+	frMul(&fr.l, &fr.l, &a.l)
+	return fr
+}
+
+// Square sets fr to fr * fr.
+func (fr *Fr) Square() *Fr {
+	frMul(&fr.l, &fr.l, &fr.l)
+	return fr
+}
+
+// Exp sets fr to fr^e, where e is a big-endian integer. It runs in time
+// that depends only on the length of e.
+func (fr *Fr) Exp(e []byte) *Fr {
+	x := fr.l
+	var t [4]uint64
+	fr.l = frOne
+	for _, b := range e {
+		for i := 7; i >= 0; i-- {
+			frMul(&fr.l, &fr.l, &fr.l)
+			frMul(&t, &fr.l, &x)
+			frSelect(&fr.l, &t, uint64(b>>uint(i))&1)
+		}
+	}
+	for i := range x {
+		x[i], t[i] = 0, 0
+	}
+	return fr
+}
+
+// Inverse sets fr to 1 / fr, or to zero if fr is zero.
+func (fr *Fr) Inverse() *Fr {
+	return fr.Exp(frModulusMinusTwo)
+}
+
+// Equal returns whether fr and a are the same element, in constant time.
+func (fr *Fr) Equal(a *Fr) bool {
+	var v uint64
+	for i := range fr.l {
+		v |= fr.l[i] ^ a.l[i]
+	}
+	return v == 0
+}
+
+func (fr *Fr) IsZero() bool {
+	return fr.Equal(&Fr{})
+}
+
+// frMul sets z to x * y / 2^256 mod r, with the CIOS method of Koç, Acar
+// and Kaliski, "Analyzing and Comparing Montgomery Multiplication
+// Algorithms". z can alias x or y.
+func frMul(z, x, y *[4]uint64) {
+	var t [4]uint64
+	var t4, t5 uint64
+	for i := 0; i < 4; i++ {
+		var c, cc uint64
+		for j := 0; j < 4; j++ {
+			t[j], c = madd(x[j], y[i], t[j], c)
+		}
+		t4, cc = add64(t4, c, 0)
+		t5 = cc
+
+		m := t[0] * frInv
+		_, c = madd(m, frModulus[0], t[0], 0)
+		for j := 1; j < 4; j++ {
+			t[j-1], c = madd(m, frModulus[j], t[j], c)
+		}
+		t[3], cc = add64(t4, c, 0)
+		t4 = t5 + cc
+	}
+	frReduce(z, &t, t4)
+}
+
+// frReduce sets z to the five-limb value t, hi, minus r if it's at least r.
+// t must be less than 2r.
+func frReduce(z, t *[4]uint64, hi uint64) {
+	var s [4]uint64
+	var borrow uint64
+	for i := range s {
+		s[i], borrow = sub64(t[i], frModulus[i], borrow)
+	}
+	_, borrow = sub64(hi, 0, borrow)
+	// If it borrowed, t < r and we keep it.
+	*z = s
+	frSelect(z, t, borrow)
+}
+
+// frSelect sets z to x if v is 1, and leaves it unchanged if v is 0.
+func frSelect(z, x *[4]uint64, v uint64) {
+	mask := -v
+	for i := range z {
+		z[i] ^= (z[i] ^ x[i]) & mask
+	}
+}
+
+// madd returns the low and high 64 bits of x * y + a + b, which can't
+// overflow 128 bits.
+func madd(x, y, a, b uint64) (lo, hi uint64) {
+	hi, lo = mul64(x, y)
+	var c uint64
+	lo, c = add64(lo, a, 0)
+	hi += c
+	lo, c = add64(lo, b, 0)
+	hi += c
+	return lo, hi
+}
+
+// add64, sub64 and mul64 are like math/bits.Add64, Sub64 and Mul64, which
+// are only available since Go 1.12, and are also constant time.
+
+func add64(x, y, carry uint64) (sum, carryOut uint64) {
+	sum = x + y + carry
+	carryOut = ((x & y) | ((x | y) &^ sum)) >> 63
+	return
+}
+
+func sub64(x, y, borrow uint64) (diff, borrowOut uint64) {
+	diff = x - y - borrow
+	borrowOut = ((^x & y) | (^(x ^ y) & diff)) >> 63
+	return
+}
+
+func mul64(x, y uint64) (hi, lo uint64) {
+	const mask32 = 1<<32 - 1
+	x0, x1 := x&mask32, x>>32
+	y0, y1 := y&mask32, y>>32
+	w0 := x0 * y0
+	t := x1*y0 + w0>>32
+	w1, w2 := t&mask32, t>>32
+	w1 += x0 * y1
+	hi = x1*y1 + w2 + w1>>32
+	lo = x * y
+	return
+}
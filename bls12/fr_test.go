@@ -0,0 +1,124 @@
+package bls12_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"github.com/FiloSottile/powersoftau/bls12"
+)
+
+func frTestValues(t *testing.T, r *big.Int) []*big.Int {
+	values := []*big.Int{
+		big.NewInt(0), big.NewInt(1), big.NewInt(2),
+		new(big.Int).Sub(r, big.NewInt(1)),
+		new(big.Int).Sub(r, big.NewInt(2)),
+		new(big.Int).Rsh(r, 1),
+		new(big.Int).Lsh(big.NewInt(1), 64),
+	}
+	for i := 0; i < 50; i++ {
+		k, err := rand.Int(rand.Reader, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		values = append(values, k)
+	}
+	return values
+}
+
+func newFr(t *testing.T, k *big.Int) *bls12.Fr {
+	fr, err := (&bls12.Fr{}).SetBytes(k.Bytes())
+	if err != nil {
+		t.Fatalf("%x: %v", k, err)
+	}
+	return fr
+}
+
+func checkFr(t *testing.T, op string, got *bls12.Fr, expected *big.Int) {
+	t.Helper()
+	var buf [32]byte
+	b := expected.Bytes()
+	if !bytes.Equal(got.Bytes(&buf), append(make([]byte, 32-len(b)), b...)) {
+		t.Errorf("%s: got %x, expected %x", op, buf, expected)
+	}
+}
+
+func TestFr(t *testing.T) {
+	r := new(big.Int).SetBytes(bls12.ScalarOrder())
+	values := frTestValues(t, r)
+	for i, a := range values {
+		x := newFr(t, a)
+		checkFr(t, "SetBytes", x, a)
+		b := values[(i+1)%len(values)]
+		y := newFr(t, b)
+
+		checkFr(t, "Add", x.Copy().Add(y), new(big.Int).Mod(new(big.Int).Add(a, b), r))
+		checkFr(t, "Sub", x.Copy().Sub(y), new(big.Int).Mod(new(big.Int).Sub(a, b), r))
+		checkFr(t, "Mul", x.Copy().Mul(y), new(big.Int).Mod(new(big.Int).Mul(a, b), r))
+		checkFr(t, "Square", x.Copy().Square(), new(big.Int).Mod(new(big.Int).Mul(a, a), r))
+		checkFr(t, "Exp", x.Copy().Exp(b.Bytes()), new(big.Int).Exp(a, b, r))
+		checkFr(t, "Exp", x.Copy().Exp([]byte{0, 0, 5}), new(big.Int).Exp(a, big.NewInt(5), r))
+		if a.Sign() == 0 {
+			checkFr(t, "Inverse", x.Copy().Inverse(), a)
+		} else {
+			checkFr(t, "Inverse", x.Copy().Inverse(), new(big.Int).ModInverse(a, r))
+		}
+
+		if !x.Equal(newFr(t, a)) || x.Equal(y) != (a.Cmp(b) == 0) {
+			t.Errorf("%x: wrong Equal", a)
+		}
+		if x.IsZero() != (a.Sign() == 0) {
+			t.Errorf("%x: wrong IsZero", a)
+		}
+	}
+
+	checkFr(t, "SetOne", (&bls12.Fr{}).SetOne(), big.NewInt(1))
+	checkFr(t, "zero value", &bls12.Fr{}, big.NewInt(0))
+	checkFr(t, "SetZero", newFr(t, new(big.Int).Rsh(r, 1)).SetZero(), big.NewInt(0))
+}
+
+func TestFrSetBytes(t *testing.T) {
+	r := new(big.Int).SetBytes(bls12.ScalarOrder())
+	for _, s := range [][]byte{
+		r.Bytes(),
+		new(big.Int).Add(r, big.NewInt(1)).Bytes(),
+		bytes.Repeat([]byte{0xff}, 32),
+		make([]byte, 33),
+	} {
+		if _, err := (&bls12.Fr{}).SetBytes(s); err == nil {
+			t.Errorf("accepted %x", s)
+		}
+		if len(s) <= 32 && bls12.IsScalar(s) {
+			t.Errorf("IsScalar accepted %x", s)
+		}
+	}
+	checkFr(t, "short", newFr(t, big.NewInt(0x1234)), big.NewInt(0x1234))
+}
+
+func TestFrScalarMult(t *testing.T) {
+	r := new(big.Int).SetBytes(bls12.ScalarOrder())
+	var buf [32]byte
+	for _, a := range frTestValues(t, r)[:10] {
+		expected := (&bls12.EP{}).SetOne().ScalarMult(a.Bytes())
+		if !(&bls12.EP{}).SetOne().ScalarMult(newFr(t, a).Bytes(&buf)).Equal(expected) {
+			t.Errorf("%x: different point", a)
+		}
+	}
+}
+
+func BenchmarkFrMul(b *testing.B) {
+	x, y := (&bls12.Fr{}).SetOne(), (&bls12.Fr{}).SetOne()
+	y.Add(y).Add(y)
+	for i := 0; i < b.N; i++ {
+		x.Mul(y)
+	}
+}
+
+func BenchmarkBigIntMulMod(b *testing.B) {
+	r := new(big.Int).SetBytes(bls12.ScalarOrder())
+	x, y := big.NewInt(1), big.NewInt(3)
+	for i := 0; i < b.N; i++ {
+		x.Mul(x, y).Mod(x, r)
+	}
+}
@@ -1,3 +1,5 @@
+#include <stdlib.h>
+
 #include "relic.h"
 #include "relic_fp.h"
 #include "relic_ep.h"
@@ -42,3 +44,60 @@ void monty_reduce(uint8_t *bin, int len) {
 
     fp_write_bin(bin, len, a);
 }
+
+// ep_read_xy reads an affine point from the big-endian x || y, and returns
+// whether it's on the curve. Unlike ep_read_bin, it never raises an error.
+int ep_read_xy(ep_t ep, const uint8_t *bin, int len) {
+    if (len != 2 * FP_BYTES) {
+        return 0;
+    }
+    fp_read_bin(ep->x, bin, FP_BYTES);
+    fp_read_bin(ep->y, bin + FP_BYTES, FP_BYTES);
+    fp_set_dig(ep->z, 1);
+    ep->norm = 1;
+    return ep_is_valid(ep);
+}
+
+void ep_read_x(ep_t ep, const uint8_t *bin, int len) {
+    ep->norm = 1;
+    fp_set_dig(ep->z, 1);
+    fp_read_bin(ep->x, bin, len);
+    fp_zero(ep->y);
+}
+
+void ep_scale_by_cofactor(ep_t p) {
+    bn_t k;
+    bn_null(k);
+    bn_new(k);
+    bn_read_str(k, "396c8c005555e1568c00aaab0000aaab", 32, 16);
+    // The point is not in the subgroup, so ep_mul can't use the endomorphism.
+    ep_mul_basic(p, p, k);
+    bn_free(k);
+}
+
+int ep_in_subgroup(const ep_t p) {
+    bn_t n;
+    ep_t t;
+    bn_null(n);
+    ep_null(t);
+    bn_new(n);
+    ep_new(t);
+    ep_curve_get_ord(n);
+    // ep_mul might reduce the scalar modulo the order, ep_mul_basic won't.
+    ep_mul_basic(t, p, n);
+    int res = ep_is_infty(t);
+    ep_free(t);
+    bn_free(n);
+    return res;
+}
+
+// ep_norm_batch normalizes the n points at p with a single field inversion.
+// Like pairing_is_one, it takes a contiguous array of ep_st.
+void ep_norm_batch(ep_st *p, int n) {
+    ep_t *ps = malloc(n * sizeof(ep_t));
+    for (int i = 0; i < n; i++) {
+        ps[i] = &p[i];
+    }
+    ep_norm_sim(ps, (const ep_t *)ps, n);
+    free(ps);
+}
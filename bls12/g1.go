@@ -5,14 +5,22 @@ package bls12
 // #include "relic_bn.h"
 // void _ep_add(ep_t r, const ep_t p, const ep_t q) { ep_add(r, p, q); }
 // void _ep_neg(ep_t r, const ep_t p) { ep_neg(r, p); }
+// void _ep_dbl(ep_t r, const ep_t p) { ep_dbl(r, p); }
 // void _ep_mul(ep_t r, const ep_t p, const bn_t k) { ep_mul(r, p, k); }
 // void _fp_rdc_monty(fp_t c, dv_t a) { fp_rdc_monty(c, a); };
 // int ep_y_is_higher(const ep_t);
+// int ep_read_xy(ep_t ep, const uint8_t *bin, int len);
+// void ep_read_x(ep_t ep, const uint8_t *bin, int len);
+// int ep_in_subgroup(const ep_t p);
+// void ep_scale_by_cofactor(ep_t p);
+// void ep_mul_ladder(ep_t r, const ep_t p, const uint8_t *k);
+// void ep_norm_batch(ep_st *p, int n);
 // void monty_reduce(uint8_t *bin, int len);
 // bn_t _bn_new();
 // void _bn_free(bn_t t);
 import "C"
 import (
+	"bytes"
 	"errors"
 )
 
@@ -44,9 +52,22 @@ func (ep *EP) ScalarMult(s []byte) *EP {
 	bn := C._bn_new()
 	defer C._bn_free(bn)
 	C.bn_read_bin(bn, (*C.uint8_t)(&s[0]), C.int(len(s)))
-	checkError()
+	mustCheckError()
 	C._ep_mul(&ep.st, &ep.st, bn)
-	checkError()
+	mustCheckError()
+	return ep
+}
+
+// ScalarMultConstantTime is like ScalarMult, but it's meant for secret
+// scalars: it uses a Montgomery ladder with a fixed number of steps and no
+// secret-dependent branches or memory accesses. s must be lower than the
+// group order, and ep must be in the subgroup.
+func (ep *EP) ScalarMultConstantTime(s []byte) *EP {
+	var k [ladderBits/8 + 1]byte
+	defer wipeLadderScalar(&k)
+	ladderScalar(&k, s)
+	C.ep_mul_ladder(&ep.st, &ep.st, (*C.uint8_t)(&k[0]))
+	mustCheckError()
 	return ep
 }
 
@@ -54,9 +75,9 @@ func (ep *EP) ScalarBaseMult(s []byte) *EP {
 	bn := C._bn_new()
 	defer C._bn_free(bn)
 	C.bn_read_bin(bn, (*C.uint8_t)(&s[0]), C.int(len(s)))
-	checkError()
+	mustCheckError()
 	C.ep_mul_gen(&ep.st, bn)
-	checkError()
+	mustCheckError()
 	return ep
 }
 
@@ -65,10 +86,43 @@ func (ep *EP) Add(a *EP) *EP {
 	return ep
 }
 
+func (ep *EP) Double() *EP {
+	C._ep_dbl(&ep.st, &ep.st)
+	return ep
+}
+
+func (ep *EP) Neg() *EP {
+	C._ep_neg(&ep.st, &ep.st)
+	return ep
+}
+
 func (ep *EP) Equal(a *EP) bool {
 	return C.ep_cmp(&ep.st, &a.st) == C.CMP_EQ
 }
 
+// ScaleByCofactor multiplies the point by the cofactor of G1, mapping any
+// point on the curve into the prime order subgroup.
+func (ep *EP) ScaleByCofactor() *EP {
+	C.ep_scale_by_cofactor(&ep.st)
+	mustCheckError()
+	return ep
+}
+
+func (ep *EP) IsZero() bool {
+	return C.ep_is_infty(&ep.st) == 1
+}
+
+// IsInSubgroup returns whether the point is in the prime order subgroup,
+// by checking that multiplying it by ScalarOrder() gives the identity.
+//
+// Decoding only checks that points are on the curve, so this must be
+// checked separately for untrusted inputs.
+func (ep *EP) IsInSubgroup() bool {
+	res := C.ep_in_subgroup(&ep.st)
+	mustCheckError()
+	return res == 1
+}
+
 const (
 	FqElementSize      = 48
 	G1CompressedSize   = FqElementSize
@@ -83,6 +137,49 @@ const (
 	serializationBigY       = 1 << 5
 )
 
+// fqModulus is the base field modulus p, big-endian.
+var fqModulus = [FqElementSize]byte{0x1a, 0x01, 0x11, 0xea, 0x39, 0x7f, 0xe6, 0x9a, 0x4b, 0x1b, 0xa7, 0xb6, 0x43, 0x4b, 0xac, 0xd7, 0x64, 0x77, 0x4b, 0x84, 0xf3, 0x85, 0x12, 0xbf, 0x67, 0x30, 0xd2, 0xa0, 0xf6, 0xb0, 0xf6, 0x24, 0x1e, 0xab, 0xff, 0xfe, 0xb1, 0x53, 0xff, 0xff, 0xb9, 0xfe, 0xff, 0xff, 0xff, 0xff, 0xaa, 0xab}
+
+// FieldModulus returns the base field modulus p, big-endian, the bound of
+// the coordinates in the encodings.
+func FieldModulus() []byte {
+	return append([]byte{}, fqModulus[:]...)
+}
+
+// isCanonical returns whether b is a sequence of big-endian field elements
+// all lower than p. relic would silently reduce them instead.
+func isCanonical(b []byte) bool {
+	for ; len(b) > 0; b = b[FqElementSize:] {
+		if bytes.Compare(b[:FqElementSize], fqModulus[:]) >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// NormalizeBatchG1 converts points to affine coordinates, which encoding
+// needs, with a single field inversion for the whole batch instead of one
+// per point, using Montgomery's trick. Points that are already affine,
+// including the point at infinity, are skipped.
+func NormalizeBatchG1(points []*EP) {
+	var ps []C.ep_st
+	var idx []int
+	for i, p := range points {
+		if p.st.norm == 0 {
+			ps = append(ps, p.st)
+			idx = append(idx, i)
+		}
+	}
+	if len(ps) == 0 {
+		return
+	}
+	C.ep_norm_batch(&ps[0], C.int(len(ps)))
+	mustCheckError()
+	for j, i := range idx {
+		points[i].st = ps[j]
+	}
+}
+
 // EncodeUncompressed encodes a point according to ebfull/pairing bls12_381
 // serialization into a byte slice of length G1UncompressedSize.
 func (ep *EP) EncodeUncompressed() []byte {
@@ -95,7 +192,7 @@ func (ep *EP) EncodeUncompressed() []byte {
 	}
 
 	C.ep_write_bin((*C.uint8_t)(&bin[0]), C.int(len(bin)), &ep.st, 0)
-	checkError()
+	mustCheckError()
 
 	return res
 }
@@ -111,9 +208,11 @@ func (ep *EP) EncodeCompressed() []byte {
 		return res
 	}
 
-	C.ep_norm(&ep.st, &ep.st)
+	if ep.st.norm == 0 {
+		C.ep_norm(&ep.st, &ep.st)
+	}
 	C.ep_write_bin((*C.uint8_t)(&bin[0]), C.int(len(bin)), &ep.st, 1)
-	checkError()
+	mustCheckError()
 
 	if C.ep_y_is_higher(&ep.st) == 1 {
 		res[0] |= serializationBigY
@@ -136,14 +235,13 @@ func (ep *EP) DecodeUncompressed(in []byte) (*EP, error) {
 		return nil, errors.New("high Y bit improperly set")
 	}
 
-	bin := make([]byte, 2*FqElementSize+1)
-	copy(bin[1:], in)
-	bin[0] = 4
-	bin[1] &= serializationMask
+	bin := make([]byte, 2*FqElementSize)
+	copy(bin, in)
+	bin[0] &= serializationMask
 
 	if in[0]&serializationInfinity != 0 {
-		for i := range bin[1:] {
-			if bin[1+i] != 0 {
+		for _, b := range bin {
+			if b != 0 {
 				return nil, errors.New("invalid infinity encoding")
 			}
 		}
@@ -151,8 +249,15 @@ func (ep *EP) DecodeUncompressed(in []byte) (*EP, error) {
 		return ep, nil
 	}
 
-	C.ep_read_bin(&ep.st, (*C.uint8_t)(&bin[0]), C.int(len(bin)))
-	checkError()
+	if !isCanonical(bin) {
+		return nil, errors.New("coordinate is not a canonical field element")
+	}
+	if C.ep_read_xy(&ep.st, (*C.uint8_t)(&bin[0]), C.int(len(bin))) == 0 {
+		return nil, errors.New("point is not on the curve")
+	}
+	if err := checkError(); err != nil {
+		return nil, err
+	}
 	return ep, nil
 }
 
@@ -166,17 +271,16 @@ func (ep *EP) DecodeCompressed(in []byte) (*EP, error) {
 		return nil, errors.New("point isn't compressed")
 	}
 
-	bin := make([]byte, FqElementSize+1)
-	copy(bin[1:], in)
-	bin[0] = 2
-	bin[1] &= serializationMask
+	bin := make([]byte, FqElementSize)
+	copy(bin, in)
+	bin[0] &= serializationMask
 
 	if in[0]&serializationInfinity != 0 {
 		if in[0]&serializationBigY != 0 {
 			return nil, errors.New("high Y bit improperly set")
 		}
-		for i := range bin[1:] {
-			if bin[1+i] != 0 {
+		for _, b := range bin {
+			if b != 0 {
 				return nil, errors.New("invalid infinity encoding")
 			}
 		}
@@ -184,9 +288,16 @@ func (ep *EP) DecodeCompressed(in []byte) (*EP, error) {
 		return ep, nil
 	}
 
-	C.ep_norm(&ep.st, &ep.st)
-	C.ep_read_bin(&ep.st, (*C.uint8_t)(&bin[0]), C.int(len(bin)))
-	checkError()
+	if !isCanonical(bin) {
+		return nil, errors.New("coordinate is not a canonical field element")
+	}
+	C.ep_read_x(&ep.st, (*C.uint8_t)(&bin[0]), C.int(len(bin)))
+	if C.ep_upk(&ep.st, &ep.st) == 0 {
+		return nil, errors.New("no square root found")
+	}
+	if err := checkError(); err != nil {
+		return nil, err
+	}
 
 	if C.ep_y_is_higher(&ep.st) == 0 {
 		if in[0]&serializationBigY != 0 {
@@ -202,5 +313,5 @@ func (ep *EP) DecodeCompressed(in []byte) (*EP, error) {
 
 func FqMontgomeryReduce(b []byte) {
 	C.monty_reduce((*C.uint8_t)(&b[0]), C.int(len(b)))
-	checkError()
+	mustCheckError()
 }
@@ -3,6 +3,7 @@ package bls12_test
 import (
 	"bytes"
 	"io/ioutil"
+	"math/big"
 	"testing"
 
 	"github.com/FiloSottile/powersoftau/bls12"
@@ -63,6 +64,114 @@ func TestVectorG1(t *testing.T) {
 	})
 }
 
+func TestInvalidG1(t *testing.T) {
+	buf := make([]byte, bls12.G1UncompressedSize)
+	buf[bls12.FqElementSize-1] = 1
+	buf[bls12.G1UncompressedSize-1] = 1
+	if _, err := (&bls12.EP{}).DecodeUncompressed(buf); err == nil {
+		t.Error("(1, 1) decoded successfully")
+	}
+
+	buf = (&bls12.EP{}).SetOne().EncodeUncompressed()
+	copy(buf, bls12.FieldModulus())
+	if _, err := (&bls12.EP{}).DecodeUncompressed(buf); err == nil {
+		t.Error("x = p decoded successfully")
+	}
+
+	buf = bls12.FieldModulus()
+	buf[0] |= 1 << 7
+	if _, err := (&bls12.EP{}).DecodeCompressed(buf); err == nil {
+		t.Error("compressed x = p decoded successfully")
+	}
+
+	failures := 0
+	for x := 0; x < 20; x++ {
+		buf := make([]byte, bls12.G1CompressedSize)
+		buf[0] = 1 << 7
+		buf[len(buf)-1] = byte(x)
+		if _, err := (&bls12.EP{}).DecodeCompressed(buf); err != nil {
+			failures++
+		}
+	}
+	if failures == 0 {
+		t.Error("no x without a square root")
+	}
+}
+
+func TestSubgroupG1(t *testing.T) {
+	if !(&bls12.EP{}).SetOne().IsInSubgroup() {
+		t.Error("generator not in subgroup")
+	}
+	if !(&bls12.EP{}).SetZero().IsInSubgroup() {
+		t.Error("identity not in subgroup")
+	}
+
+	for x := 1; x < 20; x++ {
+		buf := make([]byte, bls12.G1CompressedSize)
+		buf[0] = 1 << 7
+		buf[len(buf)-1] = byte(x)
+		p, err := (&bls12.EP{}).DecodeCompressed(buf)
+		if err != nil {
+			continue
+		}
+		if p.IsInSubgroup() {
+			t.Errorf("x = %d: point not multiplied by the cofactor is in the subgroup", x)
+		}
+		if !p.ScaleByCofactor().IsInSubgroup() {
+			t.Errorf("x = %d: point multiplied by the cofactor is not in the subgroup", x)
+		}
+		return
+	}
+	t.Error("no valid x found")
+}
+
+func TestScalarMultConstantTimeG1(t *testing.T) {
+	p := (&bls12.EP{}).ScalarBaseMult(randomScalar(t))
+	for _, s := range testScalars(t) {
+		exp := p.Copy().ScalarMult(s)
+		if got := p.Copy().ScalarMultConstantTime(s); !got.Equal(exp) {
+			t.Errorf("%x: different point", s)
+		}
+	}
+}
+
+func TestNormalizeBatchG1(t *testing.T) {
+	one := (&bls12.EP{}).SetOne()
+	var points []*bls12.EP
+	for i := 0; i < 20; i++ {
+		// Sums are left in projective coordinates.
+		points = append(points, one.Copy().ScalarMult([]byte{byte(i)}).Add(one))
+	}
+	points = append(points, one.Copy(), (&bls12.EP{}).SetZero())
+	var exp [][]byte
+	for _, p := range points {
+		exp = append(exp, p.Copy().EncodeCompressed())
+	}
+	bls12.NormalizeBatchG1(points)
+	bls12.NormalizeBatchG1(points)
+	for i, p := range points {
+		if !bytes.Equal(p.EncodeCompressed(), exp[i]) {
+			t.Errorf("%d: different encoding", i)
+		}
+	}
+}
+
+// testScalars returns 0, 1, r - 1, and some random scalars, as 32 bytes.
+func testScalars(t *testing.T) [][]byte {
+	r := (&big.Int{}).SetBytes(bls12.ScalarOrder())
+	var scalars [][]byte
+	for _, k := range []*big.Int{big.NewInt(0), big.NewInt(1), r.Sub(r, big.NewInt(1))} {
+		s := make([]byte, 32)
+		b := k.Bytes()
+		copy(s[32-len(b):], b)
+		scalars = append(scalars, s)
+	}
+	for i := 0; i < 10; i++ {
+		scalars = append(scalars, randomScalar(t))
+	}
+	return scalars
+}
+
 func readFile(t *testing.T, name string) []byte {
 	t.Helper()
 	res, err := ioutil.ReadFile(name)
@@ -44,3 +44,39 @@ void ep2_read_x(ep2_t a, uint8_t* bin, int len) {
     fp2_read_bin(a->x, bin, len);
     fp2_zero(a->y);
 }
+
+// ep2_read_xy reads an affine point from x || y, each encoded like in
+// ep2_read_x, and returns whether it's on the curve. Unlike ep2_read_bin,
+// it never raises an error.
+int ep2_read_xy(ep2_t a, uint8_t* bin, int len) {
+    if (len != 4 * FP_BYTES) {
+        return 0;
+    }
+    a->norm = 1;
+    fp_set_dig(a->z[0], 1);
+    fp_zero(a->z[1]);
+    fp2_read_bin(a->x, bin, 2 * FP_BYTES);
+    fp2_read_bin(a->y, bin + 2 * FP_BYTES, 2 * FP_BYTES);
+    return ep2_is_valid(a);
+}
+
+int ep2_in_subgroup(ep2_t p) {
+    bn_t n;
+    ep2_t t;
+    bn_null(n);
+    ep2_null(t);
+    bn_new(n);
+    ep2_new(t);
+    ep2_curve_get_ord(n);
+    // https://github.com/relic-toolkit/relic/issues/64
+    ep2_mul_basic(t, p, n);
+    int res = ep2_is_infty(t);
+    ep2_free(t);
+    bn_free(n);
+    return res;
+}
+
+// ep2_norm_batch normalizes the n points at p with a single field inversion.
+void ep2_norm_batch(ep2_t *p, int n) {
+    ep2_norm_sim(p, p, n);
+}
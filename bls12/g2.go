@@ -6,11 +6,16 @@ package bls12
 // void _ep2_free(ep2_t t) { ep2_free(t); }
 // void _ep2_add(ep2_t r, const ep2_t p, const ep2_t q) { ep2_add(r, p, q); }
 // void _ep2_neg(ep2_t r, const ep2_t p) { ep2_neg(r, p); }
+// void _ep2_dbl(ep2_t r, const ep2_t p) { ep2_dbl(r, p); }
 // void _ep2_mul(ep2_t r, const ep2_t p, const bn_t k) { ep2_mul(r, p, k); }
 // int ep2_y_is_higher(const ep2_t ep2);
 // void ep2_read_x(ep2_t ep2, uint8_t* bin, int len);
+// int ep2_read_xy(ep2_t ep2, uint8_t* bin, int len);
+// int ep2_in_subgroup(ep2_t p);
 // void ep2_mul_cof_b12(ep2_t r, ep2_t p); // unexported, don't @ me
 // void ep2_scale_by_cofactor(ep2_t p);
+// void ep2_mul_ladder(ep2_t r, ep2_t p, const uint8_t *k);
+// void ep2_norm_batch(ep2_t *p, int n);
 // bn_t _bn_new();
 // void _bn_free(bn_t t);
 import "C"
@@ -25,7 +30,7 @@ type EP2 struct {
 
 func NewEP2() *EP2 {
 	ep2 := &EP2{C._ep2_new()}
-	checkError()
+	mustCheckError()
 	return ep2
 }
 
@@ -46,13 +51,40 @@ func (ep2 *EP2) SetOne() *EP2 {
 	return ep2
 }
 
+func (ep2 *EP2) Copy() *EP2 {
+	a := NewEP2()
+	C.ep2_copy(a.t, ep2.t)
+	return a
+}
+
 func (ep2 *EP2) ScalarMult(s []byte) *EP2 {
 	bn := C._bn_new()
 	defer C._bn_free(bn)
 	C.bn_read_bin(bn, (*C.uint8_t)(&s[0]), C.int(len(s)))
-	checkError()
+	mustCheckError()
 	C._ep2_mul(ep2.t, ep2.t, bn)
-	checkError()
+	mustCheckError()
+	return ep2
+}
+
+func (ep2 *EP2) ScalarBaseMult(s []byte) *EP2 {
+	bn := C._bn_new()
+	defer C._bn_free(bn)
+	C.bn_read_bin(bn, (*C.uint8_t)(&s[0]), C.int(len(s)))
+	mustCheckError()
+	C.ep2_mul_gen(ep2.t, bn)
+	mustCheckError()
+	return ep2
+}
+
+// ScalarMultConstantTime is like ScalarMult, but for secret scalars. See
+// EP.ScalarMultConstantTime.
+func (ep2 *EP2) ScalarMultConstantTime(s []byte) *EP2 {
+	var k [ladderBits/8 + 1]byte
+	defer wipeLadderScalar(&k)
+	ladderScalar(&k, s)
+	C.ep2_mul_ladder(ep2.t, ep2.t, (*C.uint8_t)(&k[0]))
+	mustCheckError()
 	return ep2
 }
 
@@ -61,6 +93,16 @@ func (ep2 *EP2) Add(a *EP2) *EP2 {
 	return ep2
 }
 
+func (ep2 *EP2) Double() *EP2 {
+	C._ep2_dbl(ep2.t, ep2.t)
+	return ep2
+}
+
+func (ep2 *EP2) Neg() *EP2 {
+	C._ep2_neg(ep2.t, ep2.t)
+	return ep2
+}
+
 func (ep2 *EP2) Equal(a *EP2) bool {
 	return C.ep2_cmp(ep2.t, a.t) == C.CMP_EQ
 }
@@ -69,7 +111,7 @@ func (ep2 *EP2) ScaleByCofactor() *EP2 {
 	// https://github.com/relic-toolkit/relic/issues/64
 	// C.ep2_mul_cof_b12(ep2.t, ep2.t)
 	C.ep2_scale_by_cofactor(ep2.t)
-	checkError()
+	mustCheckError()
 	return ep2
 }
 
@@ -77,12 +119,38 @@ func (ep2 *EP2) IsZero() bool {
 	return C.ep2_is_infty(ep2.t) == 1
 }
 
+// IsInSubgroup returns whether the point is in the prime order subgroup,
+// by checking that multiplying it by ScalarOrder() gives the identity.
+//
+// Decoding only checks that points are on the curve, so this must be
+// checked separately for untrusted inputs.
+func (ep2 *EP2) IsInSubgroup() bool {
+	res := C.ep2_in_subgroup(ep2.t)
+	mustCheckError()
+	return res == 1
+}
+
 const (
 	Fq2ElementSize     = 96
 	G2CompressedSize   = Fq2ElementSize
 	G2UncompressedSize = 2 * Fq2ElementSize
 )
 
+// NormalizeBatchG2 is like NormalizeBatchG1, but for G2.
+func NormalizeBatchG2(points []*EP2) {
+	var ps []C.ep2_t
+	for _, p := range points {
+		if p.t.norm == 0 {
+			ps = append(ps, p.t)
+		}
+	}
+	if len(ps) == 0 {
+		return
+	}
+	C.ep2_norm_batch(&ps[0], C.int(len(ps)))
+	mustCheckError()
+}
+
 // EncodeUncompressed encodes a point according to ebfull/pairing bls12_381
 // serialization into a byte slice of length G2UncompressedSize.
 func (ep2 *EP2) EncodeUncompressed() []byte {
@@ -95,7 +163,7 @@ func (ep2 *EP2) EncodeUncompressed() []byte {
 	}
 
 	C.ep2_write_bin((*C.uint8_t)(&bin[0]), C.int(len(bin)), ep2.t, 0)
-	checkError()
+	mustCheckError()
 
 	return swapLimbs(make([]byte, 0, 2*Fq2ElementSize), res)
 }
@@ -111,9 +179,11 @@ func (ep2 *EP2) EncodeCompressed() []byte {
 		return res
 	}
 
-	C.ep2_norm(ep2.t, ep2.t)
+	if ep2.t.norm == 0 {
+		C.ep2_norm(ep2.t, ep2.t)
+	}
 	C.ep2_write_bin((*C.uint8_t)(&bin[0]), C.int(len(bin)), ep2.t, 1)
-	checkError()
+	mustCheckError()
 
 	res = swapLimbs(make([]byte, 0, Fq2ElementSize), res)
 
@@ -152,14 +222,12 @@ func (ep2 *EP2) DecodeUncompressed(in []byte) (*EP2, error) {
 		return nil, errors.New("high Y bit improperly set")
 	}
 
-	bin := make([]byte, 1, 2*Fq2ElementSize+1)
-	bin[0] = 4
-	bin = swapLimbs(bin, in)
-	bin[Fq2ElementSize/2+1] &= serializationMask
+	bin := swapLimbs(make([]byte, 0, 2*Fq2ElementSize), in)
+	bin[Fq2ElementSize/2] &= serializationMask
 
 	if in[0]&serializationInfinity != 0 {
-		for i := range bin[1:] {
-			if bin[1+i] != 0 {
+		for _, b := range bin {
+			if b != 0 {
 				return nil, errors.New("invalid infinity encoding")
 			}
 		}
@@ -167,8 +235,15 @@ func (ep2 *EP2) DecodeUncompressed(in []byte) (*EP2, error) {
 		return ep2, nil
 	}
 
-	C.ep2_read_bin(ep2.t, (*C.uint8_t)(&bin[0]), C.int(len(bin)))
-	checkError()
+	if !isCanonical(bin) {
+		return nil, errors.New("coordinate is not a canonical field element")
+	}
+	if C.ep2_read_xy(ep2.t, (*C.uint8_t)(&bin[0]), C.int(len(bin))) == 0 {
+		return nil, errors.New("point is not on the curve")
+	}
+	if err := checkError(); err != nil {
+		return nil, err
+	}
 	return ep2, nil
 }
 
@@ -198,10 +273,16 @@ func (ep2 *EP2) DecodeCompressed(in []byte) (*EP2, error) {
 		return ep2, nil
 	}
 
+	if !isCanonical(bin) {
+		return nil, errors.New("coordinate is not a canonical field element")
+	}
 	C.ep2_read_x(ep2.t, (*C.uint8_t)(&bin[0]), C.int(len(bin)))
 	if C.ep2_upk(ep2.t, ep2.t) == 0 {
 		return nil, errors.New("no square root found")
 	}
+	if err := checkError(); err != nil {
+		return nil, err
+	}
 
 	if C.ep2_y_is_higher(ep2.t) == 0 {
 		if in[0]&serializationBigY != 0 {
@@ -67,3 +67,92 @@ func TestVectorG2(t *testing.T) {
 		}
 	})
 }
+
+func TestInvalidG2(t *testing.T) {
+	buf := make([]byte, bls12.G2UncompressedSize)
+	for i := bls12.FqElementSize - 1; i < len(buf); i += bls12.FqElementSize {
+		buf[i] = 1
+	}
+	if _, err := bls12.NewEP2().DecodeUncompressed(buf); err == nil {
+		t.Error("(1 + u, 1 + u) decoded successfully")
+	}
+
+	one := bls12.NewEP2().SetOne()
+	defer one.Close()
+	buf = one.EncodeUncompressed()
+	copy(buf, bls12.FieldModulus())
+	if _, err := bls12.NewEP2().DecodeUncompressed(buf); err == nil {
+		t.Error("x.c1 = p decoded successfully")
+	}
+
+	buf = one.EncodeCompressed()
+	copy(buf[bls12.FqElementSize:], bls12.FieldModulus())
+	if _, err := bls12.NewEP2().DecodeCompressed(buf); err == nil {
+		t.Error("compressed x.c0 = p decoded successfully")
+	}
+}
+
+func TestSubgroupG2(t *testing.T) {
+	one := bls12.NewEP2().SetOne()
+	defer one.Close()
+	if !one.IsInSubgroup() {
+		t.Error("generator not in subgroup")
+	}
+
+	for x := 1; x < 20; x++ {
+		buf := make([]byte, bls12.G2CompressedSize)
+		buf[0] = 1 << 7
+		buf[len(buf)-1] = byte(x)
+		p, err := bls12.NewEP2().DecodeCompressed(buf)
+		if err != nil {
+			continue
+		}
+		if p.IsInSubgroup() {
+			t.Errorf("x = %d: point not multiplied by the cofactor is in the subgroup", x)
+		}
+		if !p.ScaleByCofactor().IsInSubgroup() {
+			t.Errorf("x = %d: point multiplied by the cofactor is not in the subgroup", x)
+		}
+		p.Close()
+		return
+	}
+	t.Error("no valid x found")
+}
+
+func TestScalarMultConstantTimeG2(t *testing.T) {
+	p := bls12.NewEP2().SetOne().ScalarMult(randomScalar(t))
+	defer p.Close()
+	for _, s := range testScalars(t) {
+		exp := p.Copy().ScalarMult(s)
+		got := p.Copy().ScalarMultConstantTime(s)
+		if !got.Equal(exp) {
+			t.Errorf("%x: different point", s)
+		}
+		exp.Close()
+		got.Close()
+	}
+}
+
+func TestNormalizeBatchG2(t *testing.T) {
+	one := bls12.NewEP2().SetOne()
+	defer one.Close()
+	var points []*bls12.EP2
+	for i := 0; i < 20; i++ {
+		points = append(points, one.Copy().ScalarMult([]byte{byte(i)}).Add(one))
+	}
+	points = append(points, one.Copy(), bls12.NewEP2().SetZero())
+	var exp [][]byte
+	for _, p := range points {
+		c := p.Copy()
+		exp = append(exp, c.EncodeCompressed())
+		c.Close()
+	}
+	bls12.NormalizeBatchG2(points)
+	bls12.NormalizeBatchG2(points)
+	for i, p := range points {
+		if !bytes.Equal(p.EncodeCompressed(), exp[i]) {
+			t.Errorf("%d: different encoding", i)
+		}
+		p.Close()
+	}
+}
@@ -0,0 +1,112 @@
+#include "relic.h"
+#include "relic_ep.h"
+#include "relic_epx.h"
+
+// The Montgomery ladders below back ScalarMultConstantTime. They take the
+// 257-bit scalar prepared by ladderScalar, whose top bit is always set, and
+// always do 256 steps of one addition and one doubling. The point in the
+// ladder to double is selected with masked swaps instead of branches or
+// table lookups. Since R1 - R0 = P throughout, the addition never hits the
+// doubling special case of the projective formulas, and it hits the infinity
+// one only with negligible probability.
+//
+// The result is left in projective coordinates: its normalization needs a
+// field inversion, which is best done for many points at once with
+// NormalizeBatchG1 or NormalizeBatchG2. The field arithmetic is still
+// relic's.
+
+#define LADDER_BITS 256
+
+// ct_swap swaps the n bytes at a and b if cond is 1, and does the same
+// memory accesses if cond is 0.
+static void ct_swap(void *a, void *b, size_t n, int cond) {
+    volatile uint8_t *x = a, *y = b;
+    uint8_t mask = -(uint8_t)cond;
+    for (size_t i = 0; i < n; i++) {
+        uint8_t t = mask & (x[i] ^ y[i]);
+        x[i] ^= t;
+        y[i] ^= t;
+    }
+}
+
+static void ct_wipe(void *a, size_t n) {
+    volatile uint8_t *x = a;
+    for (size_t i = 0; i < n; i++) {
+        x[i] = 0;
+    }
+}
+
+// With ALLOC=DYNAMIC the coordinates of an ep2_st are pointers to limbs
+// on the heap, so swapping or wiping the struct itself would only swap or
+// clear the pointers. ep2_ct_swap and ep2_ct_wipe work on the limbs instead.
+// (An ep_st holds its limbs inline, so G1 can use the whole struct.)
+
+static void ep2_ct_swap(ep2_t a, ep2_t b, int cond) {
+    for (int i = 0; i < 2; i++) {
+        ct_swap(a->x[i], b->x[i], FP_DIGS * sizeof(dig_t), cond);
+        ct_swap(a->y[i], b->y[i], FP_DIGS * sizeof(dig_t), cond);
+        ct_swap(a->z[i], b->z[i], FP_DIGS * sizeof(dig_t), cond);
+    }
+    ct_swap(&a->norm, &b->norm, sizeof(a->norm), cond);
+}
+
+static void ep2_ct_wipe(ep2_t a) {
+    for (int i = 0; i < 2; i++) {
+        ct_wipe(a->x[i], FP_DIGS * sizeof(dig_t));
+        ct_wipe(a->y[i], FP_DIGS * sizeof(dig_t));
+        ct_wipe(a->z[i], FP_DIGS * sizeof(dig_t));
+    }
+    ct_wipe(&a->norm, sizeof(a->norm));
+}
+
+static int ladder_bit(const uint8_t *k, int i) {
+    return (k[LADDER_BITS / 8 - i / 8] >> (i % 8)) & 1;
+}
+
+void ep_mul_ladder(ep_t r, const ep_t p, const uint8_t *k) {
+    ep_t r0, r1;
+    ep_null(r0);
+    ep_null(r1);
+    ep_new(r0);
+    ep_new(r1);
+
+    ep_norm(r0, p);
+    ep_dbl(r1, r0);
+    for (int i = LADDER_BITS - 1; i >= 0; i--) {
+        int b = ladder_bit(k, i);
+        ct_swap(r0, r1, sizeof(ep_st), b);
+        ep_add(r1, r0, r1);
+        ep_dbl(r0, r0);
+        ct_swap(r0, r1, sizeof(ep_st), b);
+    }
+    ep_copy(r, r0);
+
+    ct_wipe(r0, sizeof(ep_st));
+    ct_wipe(r1, sizeof(ep_st));
+    ep_free(r0);
+    ep_free(r1);
+}
+
+void ep2_mul_ladder(ep2_t r, ep2_t p, const uint8_t *k) {
+    ep2_t r0, r1;
+    ep2_null(r0);
+    ep2_null(r1);
+    ep2_new(r0);
+    ep2_new(r1);
+
+    ep2_norm(r0, p);
+    ep2_dbl(r1, r0);
+    for (int i = LADDER_BITS - 1; i >= 0; i--) {
+        int b = ladder_bit(k, i);
+        ep2_ct_swap(r0, r1, b);
+        ep2_add(r1, r0, r1);
+        ep2_dbl(r0, r0);
+        ep2_ct_swap(r0, r1, b);
+    }
+    ep2_copy(r, r0);
+
+    ep2_ct_wipe(r0);
+    ep2_ct_wipe(r1);
+    ep2_free(r0);
+    ep2_free(r1);
+}
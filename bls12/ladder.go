@@ -0,0 +1,45 @@
+package bls12
+
+// ladderBits is the number of ladder steps of ScalarMultConstantTime.
+const ladderBits = 256
+
+// rTimes3 is three times the group order, big-endian.
+var rTimes3 = [ladderBits/8 + 1]byte{
+	0x01, 0x5b, 0xc8, 0xf5, 0xf9, 0x7c, 0xd8, 0x77,
+	0xd8, 0x99, 0xad, 0x88, 0x18, 0x1c, 0xe5, 0x88,
+	0x0f, 0xfb, 0x38, 0xec, 0x08, 0xff, 0xfb, 0x13,
+	0xfc, 0xff, 0xff, 0xff, 0xfd, 0x00, 0x00, 0x00,
+	0x03,
+}
+
+// ladderScalar returns s + 3r in k, big-endian. For any s < r, that's in
+// [2^256, 2^257), so the ladder can always start from the point itself at
+// bit 256 and do exactly ladderBits steps, without ever touching the point
+// at infinity. Since the points are in the subgroup, the result is the same.
+//
+// The addition is done in constant time, and only the length of s, which
+// is not secret, affects the memory access pattern.
+func ladderScalar(k *[ladderBits/8 + 1]byte, s []byte) {
+	if len(s) > len(k)-1 {
+		panic("bls12: scalar too long for ScalarMultConstantTime")
+	}
+	var carry uint16
+	for i := len(k) - 1; i >= 0; i-- {
+		var si uint16
+		if j := i - (len(k) - len(s)); j >= 0 {
+			si = uint16(s[j])
+		}
+		sum := si + uint16(rTimes3[i]) + carry
+		k[i], carry = byte(sum), sum>>8
+	}
+	if k[0] != 1 {
+		panic("bls12: scalar too large for ScalarMultConstantTime")
+	}
+}
+
+// wipeLadderScalar zeroes k.
+func wipeLadderScalar(k *[ladderBits/8 + 1]byte) {
+	for i := range k {
+		k[i] = 0
+	}
+}
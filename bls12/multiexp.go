@@ -0,0 +1,171 @@
+package bls12
+
+import (
+	"math/bits"
+	"runtime"
+	"sync"
+)
+
+// MultiExp computes multi-scalar multiplications, that is sums of points
+// each multiplied by its own scalar, with the bucketed Pippenger algorithm.
+type MultiExp struct {
+	// Window is the size in bits of the scalar windows. If zero, a size
+	// suitable for the number of points is used.
+	Window int
+
+	// Workers is the number of goroutines the windows are spread across.
+	// If zero, runtime.NumCPU() is used.
+	Workers int
+}
+
+// MultiExpG1 returns the sum of points[i] * scalars[i]. Scalars are
+// big-endian, like the ones taken by ScalarMult, and can have different
+// lengths.
+func MultiExpG1(points []*EP, scalars [][]byte) *EP {
+	return (&MultiExp{}).G1(points, scalars)
+}
+
+// MultiExpG2 is like MultiExpG1, but for G2.
+func MultiExpG2(points []*EP2, scalars [][]byte) *EP2 {
+	return (&MultiExp{}).G2(points, scalars)
+}
+
+// G1 is like MultiExpG1, but uses the parameters in m.
+func (m *MultiExp) G1(points []*EP, scalars [][]byte) *EP {
+	c, windows := m.setup(len(points), scalars)
+
+	sums := make([]*EP, windows)
+	m.forEachWindow(windows, func(w int) {
+		buckets := make([]EP, 1<<uint(c)-1)
+		for i := range buckets {
+			buckets[i].SetZero()
+		}
+		for i, p := range points {
+			if k := scalarWindow(scalars[i], w*c, c); k != 0 {
+				buckets[k-1].Add(p)
+			}
+		}
+
+		// sum = Σ k * buckets[k-1] = Σ_k Σ_{j >= k} buckets[j-1]
+		running, sum := (&EP{}).SetZero(), (&EP{}).SetZero()
+		for k := len(buckets) - 1; k >= 0; k-- {
+			running.Add(&buckets[k])
+			sum.Add(running)
+		}
+		sums[w] = sum
+	})
+
+	res := (&EP{}).SetZero()
+	for w := windows - 1; w >= 0; w-- {
+		for i := 0; i < c; i++ {
+			res.Double()
+		}
+		res.Add(sums[w])
+	}
+	return res
+}
+
+// G2 is like MultiExpG2, but uses the parameters in m.
+func (m *MultiExp) G2(points []*EP2, scalars [][]byte) *EP2 {
+	c, windows := m.setup(len(points), scalars)
+
+	sums := make([]*EP2, windows)
+	m.forEachWindow(windows, func(w int) {
+		buckets := make([]*EP2, 1<<uint(c)-1)
+		for i := range buckets {
+			buckets[i] = NewEP2().SetZero()
+		}
+		for i, p := range points {
+			if k := scalarWindow(scalars[i], w*c, c); k != 0 {
+				buckets[k-1].Add(p)
+			}
+		}
+
+		running, sum := NewEP2().SetZero(), NewEP2().SetZero()
+		for k := len(buckets) - 1; k >= 0; k-- {
+			running.Add(buckets[k])
+			sum.Add(running)
+			buckets[k].Close()
+		}
+		running.Close()
+		sums[w] = sum
+	})
+
+	res := NewEP2().SetZero()
+	for w := windows - 1; w >= 0; w-- {
+		for i := 0; i < c; i++ {
+			res.Double()
+		}
+		res.Add(sums[w])
+		sums[w].Close()
+	}
+	return res
+}
+
+// setup returns the window size and the number of windows.
+func (m *MultiExp) setup(points int, scalars [][]byte) (c, windows int) {
+	if points != len(scalars) {
+		panic("bls12: multi-exponentiation with different number of points and scalars")
+	}
+
+	c = m.Window
+	if c == 0 {
+		c = bits.Len(uint(points)) * 2 / 3
+		if c < 1 {
+			c = 1
+		}
+		if c > 16 {
+			c = 16
+		}
+	}
+
+	maxBits := 0
+	for _, s := range scalars {
+		if len(s)*8 > maxBits {
+			maxBits = len(s) * 8
+		}
+	}
+	windows = (maxBits + c - 1) / c
+	return c, windows
+}
+
+// forEachWindow calls f for each window in [0, windows) from m.Workers
+// goroutines, and waits for them to complete.
+func (m *MultiExp) forEachWindow(windows int, f func(w int)) {
+	workers := m.Workers
+	if workers == 0 {
+		workers = runtime.NumCPU()
+	}
+
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			for w := range work {
+				f(w)
+			}
+			wg.Done()
+		}()
+	}
+	for w := 0; w < windows; w++ {
+		work <- w
+	}
+	close(work)
+	wg.Wait()
+}
+
+// scalarWindow returns the c bits of the big-endian scalar s starting at
+// the bit with index start, counting from the least significant one.
+func scalarWindow(s []byte, start, c int) int {
+	res := 0
+	for i := 0; i < c; i++ {
+		bit := start + i
+		idx := len(s) - 1 - bit/8
+		if idx < 0 {
+			break
+		}
+		res |= int(s[idx]>>uint(bit%8)&1) << uint(i)
+	}
+	return res
+}
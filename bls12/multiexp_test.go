@@ -0,0 +1,81 @@
+package bls12_test
+
+import (
+	"crypto/rand"
+	"fmt"
+	"testing"
+
+	"github.com/FiloSottile/powersoftau/bls12"
+)
+
+func randomScalars(t *testing.T, n int) [][]byte {
+	scalars := make([][]byte, n)
+	for i := range scalars {
+		// Mix lengths, and make some scalars zero.
+		scalars[i] = make([]byte, 1+i%31)
+		if i%7 != 0 {
+			if _, err := rand.Read(scalars[i]); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return scalars
+}
+
+func TestMultiExpG1(t *testing.T) {
+	for _, n := range []int{0, 1, 2, 100, 1000} {
+		points := make([]*bls12.EP, n)
+		for i := range points {
+			points[i] = (&bls12.EP{}).SetOne().ScalarMult([]byte{byte(i), 1})
+		}
+		scalars := randomScalars(t, n)
+
+		expected := (&bls12.EP{}).SetZero()
+		for i := range points {
+			expected.Add(points[i].Copy().ScalarMult(scalars[i]))
+		}
+
+		for _, m := range []*bls12.MultiExp{{}, {Window: 1, Workers: 1}, {Window: 5, Workers: 3}, {Window: 13}} {
+			t.Run(fmt.Sprintf("%d/%d/%d", n, m.Window, m.Workers), func(t *testing.T) {
+				if !m.G1(points, scalars).Equal(expected) {
+					t.Error("wrong result")
+				}
+			})
+		}
+		if !bls12.MultiExpG1(points, scalars).Equal(expected) {
+			t.Errorf("%d: wrong result from MultiExpG1", n)
+		}
+	}
+}
+
+func TestMultiExpG2(t *testing.T) {
+	for _, n := range []int{0, 1, 2, 100} {
+		points := make([]*bls12.EP2, n)
+		for i := range points {
+			points[i] = bls12.NewEP2().SetOne().ScalarMult([]byte{byte(i), 1})
+		}
+		scalars := randomScalars(t, n)
+
+		expected := bls12.NewEP2().SetZero()
+		for i := range points {
+			p := points[i].Copy().ScalarMult(scalars[i])
+			expected.Add(p)
+			p.Close()
+		}
+
+		for _, m := range []*bls12.MultiExp{{}, {Window: 1, Workers: 1}, {Window: 5, Workers: 3}} {
+			t.Run(fmt.Sprintf("%d/%d/%d", n, m.Window, m.Workers), func(t *testing.T) {
+				res := m.G2(points, scalars)
+				if !res.Equal(expected) {
+					t.Error("wrong result")
+				}
+				res.Close()
+			})
+		}
+
+		expected.Close()
+		for _, p := range points {
+			p.Close()
+		}
+	}
+}
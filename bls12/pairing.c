@@ -0,0 +1,71 @@
+#include <stdlib.h>
+
+#include "relic.h"
+#include "relic_pp.h"
+
+// fp12_t is an array type, so we box it to be able to hold it from Go.
+
+typedef struct gt_box {
+    fp12_t v;
+} gt_box;
+
+gt_box *gt_box_new() {
+    gt_box *g = malloc(sizeof(gt_box));
+    fp12_null(g->v);
+    fp12_new(g->v);
+    fp12_set_dig(g->v, 1);
+    return g;
+}
+
+void gt_box_free(gt_box *g) {
+    fp12_free(g->v);
+    free(g);
+}
+
+void gt_box_pair(gt_box *r, ep_st *p, ep2_t q) {
+    pp_map_k12(r->v, p, q);
+}
+
+void gt_box_mul(gt_box *r, gt_box *a) {
+    fp12_mul(r->v, r->v, a->v);
+}
+
+// gt_box_write encodes a big-endian, from c1.c2.c1 down to c0.c0.c0, so
+// that like in the G2 encoding the c1 of each Fq2 element comes first.
+void gt_box_write(uint8_t *bin, gt_box *a) {
+    for (int i = 0; i < 2; i++) {
+        for (int j = 0; j < 3; j++) {
+            for (int k = 0; k < 2; k++) {
+                int n = (1 - i) * 6 + (2 - j) * 2 + (1 - k);
+                fp_write_bin(bin + n * FP_BYTES, FP_BYTES, a->v[i][j][k]);
+            }
+        }
+    }
+}
+
+int gt_box_equal(gt_box *a, gt_box *b) {
+    return fp12_cmp(a->v, b->v) == CMP_EQ;
+}
+
+int gt_box_is_one(gt_box *a) {
+    return fp12_cmp_dig(a->v, 1) == CMP_EQ;
+}
+
+// pairing_is_one takes a contiguous array of ep_st because Go can't pass
+// an array of pointers to Go memory.
+int pairing_is_one(ep_st *p, ep2_t *q, int m) {
+    ep_t *ps = malloc(m * sizeof(ep_t));
+    for (int i = 0; i < m; i++) {
+        ps[i] = &p[i];
+    }
+
+    fp12_t r;
+    fp12_null(r);
+    fp12_new(r);
+    pp_map_sim_k12(r, ps, q, m);
+    int res = fp12_cmp_dig(r, 1) == CMP_EQ;
+    fp12_free(r);
+
+    free(ps);
+    return res;
+}
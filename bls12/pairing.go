@@ -0,0 +1,93 @@
+package bls12
+
+// #include "relic_core.h"
+// #include "relic_pp.h"
+// typedef struct gt_box gt_box;
+// gt_box *gt_box_new();
+// void gt_box_free(gt_box *g);
+// void gt_box_pair(gt_box *r, ep_st *p, ep2_t q);
+// void gt_box_mul(gt_box *r, gt_box *a);
+// void gt_box_write(uint8_t *bin, gt_box *a);
+// int gt_box_equal(gt_box *a, gt_box *b);
+// int gt_box_is_one(gt_box *a);
+// int pairing_is_one(ep_st *p, ep2_t *q, int m);
+import "C"
+
+// GT is an element of the target group backed by a relic fp12_t.
+//
+// GT requires manual memory management.
+type GT struct {
+	b *C.gt_box
+}
+
+// NewGT returns a new GT element set to one.
+func NewGT() *GT {
+	gt := &GT{C.gt_box_new()}
+	mustCheckError()
+	return gt
+}
+
+func (gt *GT) Close() {
+	C.gt_box_free(gt.b)
+}
+
+// Pair returns the optimal ate pairing e(p, q).
+func Pair(p *EP, q *EP2) *GT {
+	gt := NewGT()
+	C.gt_box_pair(gt.b, &p.st, q.t)
+	mustCheckError()
+	return gt
+}
+
+func (gt *GT) Mul(a *GT) *GT {
+	C.gt_box_mul(gt.b, a.b)
+	mustCheckError()
+	return gt
+}
+
+// GTSize is the length of the encoding returned by GT.Bytes.
+const GTSize = 12 * FqElementSize
+
+// Bytes returns the encoding of gt as twelve big-endian Fq elements. GT is
+// Fq12 = Fq6[w], Fq6 = Fq2[v] and Fq2 = Fq[u], and at every level the
+// coefficients are written from the highest to the lowest: c1 then c0 of
+// Fq12, c2, c1 then c0 of each Fq6, and c1 then c0 of each Fq2. That is,
+// c1.c2.c1, c1.c2.c0, c1.c1.c1, ..., c0.c0.c0, the same order as the GT
+// encoding of kilic/bls12-381.
+func (gt *GT) Bytes() []byte {
+	buf := make([]byte, GTSize)
+	C.gt_box_write((*C.uint8_t)(&buf[0]), gt.b)
+	mustCheckError()
+	return buf
+}
+
+func (gt *GT) Equal(a *GT) bool {
+	return C.gt_box_equal(gt.b, a.b) == 1
+}
+
+func (gt *GT) IsOne() bool {
+	return C.gt_box_is_one(gt.b) == 1
+}
+
+// PairingCheck returns true if the product of e(g1[i], g2[i]) is one.
+//
+// It computes the Miller loops of all pairs simultaneously, and performs a
+// single final exponentiation, so it's much faster than multiplying the
+// results of Pair.
+func PairingCheck(g1 []*EP, g2 []*EP2) bool {
+	if len(g1) != len(g2) {
+		panic("bls12: PairingCheck called with slices of different length")
+	}
+	if len(g1) == 0 {
+		return true
+	}
+	ps := make([]C.ep_st, len(g1))
+	qs := make([]C.ep2_t, len(g2))
+	for i := range g1 {
+		C.ep_copy(&ps[i], &g1[i].st)
+		qs[i] = g2[i].t
+	}
+	res := C.pairing_is_one(&ps[0], &qs[0], C.int(len(ps)))
+	mustCheckError()
+	return res == 1
+}
@@ -0,0 +1,162 @@
+package bls12_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/FiloSottile/powersoftau/bls12"
+)
+
+// vectorsG1 returns the points i * G1 for i < 1000 from the test vectors.
+func vectorsG1(t *testing.T) []*bls12.EP {
+	data := readFile(t, "testdata/g1_uncompressed_valid_test_vectors.dat")
+	var res []*bls12.EP
+	for i := 0; i < 1000; i++ {
+		p, err := (&bls12.EP{}).DecodeUncompressed(data[:bls12.G1UncompressedSize])
+		if err != nil {
+			t.Fatalf("%d: failed decoding: %v", i, err)
+		}
+		res = append(res, p)
+		data = data[bls12.G1UncompressedSize:]
+	}
+	return res
+}
+
+// vectorsG2 returns the points i * G2 for i < 1000 from the test vectors.
+func vectorsG2(t *testing.T) []*bls12.EP2 {
+	data := readFile(t, "testdata/g2_uncompressed_valid_test_vectors.dat")
+	var res []*bls12.EP2
+	for i := 0; i < 1000; i++ {
+		p, err := bls12.NewEP2().DecodeUncompressed(data[:bls12.G2UncompressedSize])
+		if err != nil {
+			t.Fatalf("%d: failed decoding: %v", i, err)
+		}
+		res = append(res, p)
+		data = data[bls12.G2UncompressedSize:]
+	}
+	return res
+}
+
+func TestPairingBilinearity(t *testing.T) {
+	g1, g2 := vectorsG1(t), vectorsG2(t)
+	defer func() {
+		for _, p := range g2 {
+			p.Close()
+		}
+	}()
+
+	base := bls12.Pair(g1[1], g2[1])
+	defer base.Close()
+	if base.IsOne() {
+		t.Fatal("e(G1, G2) is one")
+	}
+
+	for _, ij := range [][2]int{{1, 1}, {2, 3}, {3, 2}, {7, 11}, {13, 17}, {31, 31}} {
+		i, j := ij[0], ij[1]
+
+		// e(i * G1, j * G2) == e(ij * G1, G2) == e(G1, ij * G2)
+		a := bls12.Pair(g1[i], g2[j])
+		b := bls12.Pair(g1[i*j], g2[1])
+		c := bls12.Pair(g1[1], g2[i*j])
+		if !a.Equal(b) || !a.Equal(c) {
+			t.Errorf("e(%d * G1, %d * G2) is not e(G1, G2)^%d", i, j, i*j)
+		}
+
+		// e(G1, G2)^ij == e(G1, G2) * ... * e(G1, G2)
+		d := bls12.NewGT()
+		for k := 0; k < i*j; k++ {
+			d.Mul(base)
+		}
+		if !a.Equal(d) {
+			t.Errorf("e(%d * G1, %d * G2) is not the product of %d e(G1, G2)", i, j, i*j)
+		}
+
+		a.Close()
+		b.Close()
+		c.Close()
+		d.Close()
+	}
+
+	a := bls12.Pair((&bls12.EP{}).SetZero(), g2[1])
+	if !a.IsOne() {
+		t.Error("e(0, G2) is not one")
+	}
+	a.Close()
+	zero := bls12.NewEP2().SetZero()
+	defer zero.Close()
+	a = bls12.Pair(g1[1], zero)
+	if !a.IsOne() {
+		t.Error("e(G1, 0) is not one")
+	}
+	a.Close()
+}
+
+// pairingGenerator is e(G1, G2) in the GT.Bytes encoding, from the
+// TestPairingExpected vector of kilic/bls12-381, which blst also produces
+// from blst_miller_loop and blst_final_exp.
+const pairingGenerator = `
+0f41e58663bf08cf068672cbd01a7ec73baca4d72ca93544deff686bfd6df543d48eaa24afe47e1efde449383b676631
+04c581234d086a9902249b64728ffd21a189e87935a954051c7cdba7b3872629a4fafc05066245cb9108f0242d0fe3ef
+03350f55a7aefcd3c31b4fcb6ce5771cc6a0e9786ab5973320c806ad360829107ba810c5a09ffdd9be2291a0c25a99a2
+11b8b424cd48bf38fcef68083b0b0ec5c81a93b330ee1a677d0d15ff7b984e8978ef48881e32fac91b93b47333e2ba57
+06fba23eb7c5af0d9f80940ca771b6ffd5857baaf222eb95a7d2809d61bfe02e1bfd1b68ff02f0b8102ae1c2d5d5ab1a
+19f26337d205fb469cd6bd15c3d5a04dc88784fbb3d0b2dbdea54d43b2b73f2cbb12d58386a8703e0f948226e47ee89d
+018107154f25a764bd3c79937a45b84546da634b8f6be14a8061e55cceba478b23f7dacaa35c8ca78beae9624045b4b6
+01b2f522473d171391125ba84dc4007cfbf2f8da752f7c74185203fcca589ac719c34dffbbaad8431dad1c1fb597aaa5
+193502b86edb8857c273fa075a50512937e0794e1e65a7617c90d8bd66065b1fffe51d7a579973b1315021ec3c19934f
+1368bb445c7c2d209703f239689ce34c0378a68e72a6b3b216da0e22a5031b54ddff57309396b38c881c4c849ec23e87
+089a1c5b46e5110b86750ec6a532348868a84045483c92b7af5af689452eafabf1a8943e50439f1d59882a98eaa0170f
+1250ebd871fc0a92a7b2d83168d0d727272d441befa15c503dd8e90ce98db3e7b6d194f60839c508a84305aaca1789b6
+`
+
+func TestPairingKnownAnswer(t *testing.T) {
+	expected, err := hex.DecodeString(strings.Replace(pairingGenerator, "\n", "", -1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g2 := bls12.NewEP2().SetOne()
+	defer g2.Close()
+	gt := bls12.Pair((&bls12.EP{}).SetOne(), g2)
+	defer gt.Close()
+	if got := gt.Bytes(); !bytes.Equal(got, expected) {
+		t.Errorf("e(G1, G2) = %x, expected %x", got, expected)
+	}
+}
+
+func TestPairingCheck(t *testing.T) {
+	g1, g2 := vectorsG1(t), vectorsG2(t)
+	defer func() {
+		for _, p := range g2 {
+			p.Close()
+		}
+	}()
+
+	if !bls12.PairingCheck(nil, nil) {
+		t.Error("empty PairingCheck failed")
+	}
+
+	// e(5 * G1, 7 * G2) * e(-35 * G1, G2) == 1
+	if !bls12.PairingCheck(
+		[]*bls12.EP{g1[5], g1[35].Copy().Neg()},
+		[]*bls12.EP2{g2[7], g2[1]}) {
+		t.Error("valid PairingCheck failed")
+	}
+
+	// e(2 * G1, 3 * G2) * e(4 * G1, 5 * G2) * e(-26 * G1, G2) == 1
+	if !bls12.PairingCheck(
+		[]*bls12.EP{g1[2], g1[4], g1[26].Copy().Neg()},
+		[]*bls12.EP2{g2[3], g2[5], g2[1]}) {
+		t.Error("valid three-way PairingCheck failed")
+	}
+
+	if bls12.PairingCheck(
+		[]*bls12.EP{g1[5], g1[36].Copy().Neg()},
+		[]*bls12.EP2{g2[7], g2[1]}) {
+		t.Error("invalid PairingCheck succeeded")
+	}
+	if bls12.PairingCheck([]*bls12.EP{g1[5]}, []*bls12.EP2{g2[7]}) {
+		t.Error("single non-degenerate PairingCheck succeeded")
+	}
+}
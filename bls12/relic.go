@@ -5,12 +5,16 @@ package bls12
 // #include "relic_core.h"
 // #include "relic_err.h"
 // bn_t _bn_new() { bn_t t; bn_new(t); return t; };
-// void _bn_free(bn_t t) { bn_free(t); };
+// void _bn_free(bn_t t) {
+//     // Scalars can be secret, so wipe all digits before freeing them.
+//     volatile dig_t *dp = t->dp;
+//     for (int i = 0; i < t->alloc; i++) dp[i] = 0;
+//     bn_free(t);
+// };
 import "C"
 import (
+	"errors"
 	"math/big"
-	"os"
-	"runtime/debug"
 )
 
 var r *big.Int
@@ -18,42 +22,50 @@ var r *big.Int
 func init() {
 	C.core_init()
 	C.ep_param_set_any_pairf()
-	checkError()
+	mustCheckError()
 	r = (&big.Int{}).SetBytes(ScalarOrder())
 }
 
-// With CHECK on, the program exits on the second uncaught(?) error,
+// With CHECK on, relic exits the program on the second uncaught error,
 // and there are functions like ep_read_bin that will cause two errors
-// in a row without returning.
+// in a row without returning. Retrieving the error with err_get_msg
+// clears it, so a single error is survivable.
 //
 // With CHECK off there is no err_get_msg.
 //
-// Basically there's nothing we can do beyond keeping CHECK on, so that
-// we see log+exit, and treat all errors as irrecoverable. YOLO.
+// So we keep CHECK on, and make sure never to call relic functions that
+// can fail twice on inputs we don't control: all decoding functions
+// validate the encoding in Go or in our C helpers before reaching relic,
+// and return an error. The remaining relic errors can only be caused by
+// bugs, and cause a panic, which unlike the old os.Exit can be recovered.
 //
-// But anyway, if by mistake we cause one error and not two, we need
-// to detonate ourselves. Sigh.
-//
-// Ah, and https://github.com/relic-toolkit/relic/issues/59.
+// And https://github.com/relic-toolkit/relic/issues/59.
 
-func checkError() {
+// checkError returns and clears the pending relic error, if any.
+func checkError() error {
 	if C.err_get_code() != C.STS_OK {
 		var e C.err_t
 		var msg *C.char
 		C.err_get_msg(&e, &msg)
-		// errors.New(C.GoString(msg))
-		debug.PrintStack()
-		os.Exit(int(e))
+		return errors.New("relic: " + C.GoString(msg))
+	}
+	return nil
+}
+
+// mustCheckError panics if relic reported an error.
+func mustCheckError() {
+	if err := checkError(); err != nil {
+		panic(err)
 	}
 }
 
 func ScalarOrder() []byte {
 	var r C.bn_st
 	C.ep2_curve_get_ord(&r)
-	checkError()
+	mustCheckError()
 	buf := make([]byte, 48)
 	C.bn_write_bin((*C.uint8_t)(&buf[0]), C.int(len(buf)), &r)
-	checkError()
+	mustCheckError()
 	return buf
 }
 
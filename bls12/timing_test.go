@@ -0,0 +1,163 @@
+package bls12_test
+
+import (
+	"crypto/rand"
+	"flag"
+	"math"
+	"math/big"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/FiloSottile/powersoftau/bls12"
+)
+
+// The timing tests follow dudect (https://eprint.iacr.org/2016/1123): time
+// an operation on inputs from two classes, a fixed one and a random one,
+// interleaved at random, and check with Welch's t-test that the two timing
+// distributions can't be told apart. They are slow and noisy, so they only
+// run with -dudect N, where N is the number of measurements, for example
+//
+//	go test ./bls12 -run Timing -args -dudect 100000
+//
+// on an otherwise idle machine.
+var dudect = flag.Int("dudect", 0, "run the timing tests with this many measurements")
+
+// dudectThreshold is the t statistic above which dudect considers a leak
+// certain, rather than just likely.
+const dudectThreshold = 10
+
+func TestTimingG1(t *testing.T) {
+	p := (&bls12.EP{}).ScalarBaseMult(randomScalar(t))
+	measure := func(s []byte) time.Duration {
+		ep := p.Copy()
+		start := time.Now()
+		ep.ScalarMultConstantTime(s)
+		return time.Since(start)
+	}
+	if tt := runDudect(t, measure); math.Abs(tt) > dudectThreshold {
+		t.Errorf("G1 ScalarMultConstantTime: t = %.2f", tt)
+	}
+	tt := runDudect(t, func(s []byte) time.Duration {
+		ep := p.Copy()
+		start := time.Now()
+		ep.ScalarMult(s)
+		return time.Since(start)
+	})
+	t.Logf("G1 ScalarMult, for comparison: t = %.2f", tt)
+}
+
+func TestTimingG2(t *testing.T) {
+	p := bls12.NewEP2().SetOne().ScalarMult(randomScalar(t))
+	defer p.Close()
+	measure := func(s []byte) time.Duration {
+		ep2 := p.Copy()
+		defer ep2.Close()
+		start := time.Now()
+		ep2.ScalarMultConstantTime(s)
+		return time.Since(start)
+	}
+	if tt := runDudect(t, measure); math.Abs(tt) > dudectThreshold {
+		t.Errorf("G2 ScalarMultConstantTime: t = %.2f", tt)
+	}
+	tt := runDudect(t, func(s []byte) time.Duration {
+		ep2 := p.Copy()
+		defer ep2.Close()
+		start := time.Now()
+		ep2.ScalarMult(s)
+		return time.Since(start)
+	})
+	t.Logf("G2 ScalarMult, for comparison: t = %.2f", tt)
+}
+
+func randomScalar(t *testing.T) []byte {
+	r := (&big.Int{}).SetBytes(bls12.ScalarOrder())
+	k, err := rand.Int(rand.Reader, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := make([]byte, 32)
+	b := k.Bytes()
+	copy(s[32-len(b):], b)
+	return s
+}
+
+// runDudect measures *dudect operations, with the fixed scalar 1 or random
+// scalars, and returns the largest t statistic over the raw measurements
+// and the ones cropped at a range of percentiles.
+func runDudect(t *testing.T, measure func(s []byte) time.Duration) float64 {
+	if *dudect == 0 {
+		t.Skip("timing tests are only run with -dudect")
+	}
+	n := *dudect
+
+	fixed := make([]byte, 32)
+	fixed[31] = 1
+	classes := make([]byte, n)
+	if _, err := rand.Read(classes); err != nil {
+		t.Fatal(err)
+	}
+	inputs := make([][]byte, n)
+	for i := range inputs {
+		classes[i] &= 1
+		if classes[i] == 0 {
+			inputs[i] = fixed
+		} else {
+			inputs[i] = randomScalar(t)
+		}
+	}
+
+	times := make([]float64, n)
+	for i, s := range inputs {
+		times[i] = float64(measure(s))
+	}
+
+	// Heavy tails from interrupts and scheduling hide differences in the
+	// bulk of the distribution, so also test the measurements below a
+	// series of percentiles, like dudect does.
+	sorted := append([]float64{}, times...)
+	sort.Float64s(sorted)
+	cutoffs := []float64{math.Inf(1)}
+	for i := 0; i < 10; i++ {
+		p := 1 - math.Pow(0.5, float64(i+1))
+		cutoffs = append(cutoffs, sorted[int(p*float64(n-1))])
+	}
+
+	var max float64
+	for _, cutoff := range cutoffs {
+		var w [2]welford
+		for i, d := range times {
+			if d <= cutoff {
+				w[classes[i]].add(d)
+			}
+		}
+		if tt := welchT(w[0], w[1]); math.Abs(tt) > math.Abs(max) {
+			max = tt
+		}
+	}
+	return max
+}
+
+// welford accumulates the mean and variance of a sample in one pass.
+type welford struct {
+	n        float64
+	mean, m2 float64
+}
+
+func (w *welford) add(x float64) {
+	w.n++
+	delta := x - w.mean
+	w.mean += delta / w.n
+	w.m2 += delta * (x - w.mean)
+}
+
+func (w *welford) variance() float64 {
+	return w.m2 / (w.n - 1)
+}
+
+func welchT(a, b welford) float64 {
+	if a.n < 2 || b.n < 2 {
+		return 0
+	}
+	return (a.mean - b.mean) / math.Sqrt(a.variance()/a.n+b.variance()/b.n)
+}
@@ -1,12 +1,17 @@
 package main
 
 import (
+	"context"
+	"encoding/hex"
 	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	_ "net/http/pprof"
+	"os"
+	"os/signal"
 	"runtime"
+	"syscall"
 
 	"github.com/FiloSottile/powersoftau/powersoftau"
 )
@@ -15,6 +20,15 @@ func main() {
 	challengeFile := flag.String("challenge", "./challenge", "path to the challenge file")
 	responseFile := flag.String("response", "./response", "path to the response file")
 	nextFile := flag.String("next", "", "path to the next challenge file, optional")
+	powers := flag.Uint("powers", 0, "base 2 logarithm of the number of powers of tau; detected from the challenge size if zero")
+	stream := flag.Bool("stream", true, "process the challenge in chunks instead of loading it all in memory")
+	checkpoint := flag.String("checkpoint", "", "path to a checkpoint file to periodically save progress to, optional; requires $TAUCOMPUTE_PASSPHRASE, under which the private key is sealed")
+	resume := flag.Bool("resume", false, "resume an interrupted computation from the -checkpoint file")
+	entropy := flag.String("entropy", "system", "comma-separated entropy sources to mix for the private key: system, keyboard, file:PATH")
+	mlock := flag.Bool("mlock", false, "keep the private key in memory locked with mlock, so that it's never written to swap")
+	beacon := flag.String("beacon", "", "hex-encoded public random beacon value; makes a reproducible beacon contribution instead of a random one")
+	beaconIterations := flag.Uint("beacon-iterations", 10, "base 2 logarithm of the number of SHA-256 iterations of the -beacon value")
+	progressMode := flag.String("progress", "auto", "progress reporting: bar, json (one line every 10 seconds), none, or auto for bar on a terminal and json otherwise")
 	pprof := flag.Bool("pprof", false, "run a profiling server; use ONLY FOR DEBUGGING")
 	flag.Parse()
 
@@ -22,25 +36,96 @@ func main() {
 		go http.ListenAndServe("localhost:6060", nil)
 	}
 
-	log.Printf("Reading challenge...\n")
-	ch, err := powersoftau.ReadChallenge(*challengeFile)
+	if !*stream && (*checkpoint != "" || *resume) {
+		log.Fatalf("-checkpoint and -resume require -stream\n")
+	}
+	if *resume && *checkpoint == "" {
+		log.Fatalf("-resume requires -checkpoint\n")
+	}
+	var passphrase []byte
+	if *checkpoint != "" {
+		passphrase = []byte(os.Getenv("TAUCOMPUTE_PASSPHRASE"))
+		if len(passphrase) == 0 {
+			log.Fatalf("-checkpoint requires a passphrase in $TAUCOMPUTE_PASSPHRASE\n")
+		}
+	}
+
+	if *mlock {
+		if err := powersoftau.LockPrivateKeys(); err != nil {
+			log.Fatalf("Failed to lock memory: %v\n", err)
+		}
+	}
+
+	progress, err := newProgressReporter(*progressMode)
 	if err != nil {
-		log.Fatalf("Failed to read the challenge: %v\n", err)
+		log.Fatalf("Invalid -progress: %v\n", err)
 	}
 
-	log.Printf("Starting computation...\n")
-	ch.Compute(runtime.NumCPU())
+	var params *powersoftau.Parameters
+	if *powers != 0 {
+		params, err = powersoftau.NewParameters(*powers)
+		if err != nil {
+			log.Fatalf("Invalid -powers: %v\n", err)
+		}
+	}
 
-	log.Printf("Writing response...\n")
-	if err := powersoftau.WriteResponse(*responseFile, ch); err != nil {
-		log.Fatalf("Failed to write the response: %v\n", err)
+	var newKeypair func(digest []byte) (*powersoftau.PublicKey, *powersoftau.PrivateKey)
+	entropySet := false
+	flag.Visit(func(f *flag.Flag) { entropySet = entropySet || f.Name == "entropy" })
+	if *beacon != "" && entropySet {
+		log.Fatalf("-beacon and -entropy can't be used together\n")
+	}
+	if *beacon == "" && !*resume {
+		source, err := powersoftau.ParseEntropySource(*entropy)
+		if err != nil {
+			log.Fatalf("Invalid -entropy: %v\n", err)
+		}
+		newKeypair, err = powersoftau.EntropyKeypair(source)
+		if err != nil {
+			log.Fatalf("Failed to collect entropy: %v\n", err)
+		}
+	}
+	if *beacon != "" {
+		value, err := hex.DecodeString(*beacon)
+		if err != nil {
+			log.Fatalf("Invalid -beacon: %v\n", err)
+		}
+		log.Printf("Hashing the beacon 2^%d times...\n", *beaconIterations)
+		seed, err := powersoftau.IterateBeacon(value, *beaconIterations)
+		if err != nil {
+			log.Fatalf("Invalid -beacon-iterations: %v\n", err)
+		}
+		log.Printf("The beacon seed is %x\n", seed)
+		newKeypair = powersoftau.BeaconKeypair(seed)
 	}
 
-	if *nextFile != "" {
-		log.Printf("Writing next challenge...\n")
-		if err := powersoftau.WriteNextChallenge(*nextFile, ch); err != nil {
-			log.Fatalf("Failed to write the next challenge: %v\n", err)
+	ctx := interruptContext()
+	var ch *powersoftau.Challenge
+	if *stream {
+		if *resume {
+			log.Printf("Resuming computation from `%s`...\n", *checkpoint)
+		} else {
+			log.Printf("Computing response...\n")
 		}
+		ch, err = powersoftau.ComputeStreamContext(ctx, *challengeFile, *responseFile, &powersoftau.StreamOptions{
+			Params:     params,
+			NextFile:   *nextFile,
+			Processes:  runtime.NumCPU(),
+			NewKeypair: newKeypair,
+			Checkpoint: *checkpoint,
+			Passphrase: passphrase,
+			Resume:     *resume,
+			Progress:   progress.Step("Computing", "points"),
+		})
+		if err == context.Canceled && *checkpoint != "" {
+			log.Fatalf("Interrupted. Run the same command with -resume added to continue.\n")
+		}
+		checkInterrupted(err)
+		if err != nil {
+			log.Fatalf("Failed to compute the response: %v\n", err)
+		}
+	} else {
+		ch = computeInMemory(ctx, *challengeFile, *responseFile, *nextFile, params, newKeypair, progress)
 	}
 
 	log.Printf("Done!\n\nYour contribution has been written to `%s`\n\nThe BLAKE2b hash of `%s` is:\n", *responseFile, *responseFile)
@@ -52,3 +137,64 @@ func main() {
 		fmt.Printf("\n")
 	}
 }
+
+func computeInMemory(ctx context.Context, challengeFile, responseFile, nextFile string, params *powersoftau.Parameters,
+	newKeypair func(digest []byte) (*powersoftau.PublicKey, *powersoftau.PrivateKey), progress *progressReporter) *powersoftau.Challenge {
+	log.Printf("Reading challenge...\n")
+	// The subgroup check is done by ComputeWithContext.
+	ch, err := powersoftau.ReadChallengeContext(ctx, challengeFile, params, false, progress.Step("Reading", "bytes"))
+	checkInterrupted(err)
+	if err != nil {
+		log.Fatalf("Failed to read the challenge: %v\n", err)
+	}
+
+	log.Printf("Starting computation...\n")
+	ch.Progress = progress.Step("Computing", "points")
+	err = ch.ComputeWithContext(ctx, runtime.NumCPU(), newKeypair)
+	checkInterrupted(err)
+	if err != nil {
+		log.Fatalf("Failed to compute the response: %v\n", err)
+	}
+
+	log.Printf("Writing response...\n")
+	ch.Progress = progress.Step("Writing", "bytes")
+	err = powersoftau.WriteResponseContext(ctx, responseFile, ch)
+	checkInterrupted(err)
+	if err != nil {
+		log.Fatalf("Failed to write the response: %v\n", err)
+	}
+
+	if nextFile != "" {
+		log.Printf("Writing next challenge...\n")
+		ch.Progress = progress.Step("Writing next", "bytes")
+		err := powersoftau.WriteNextChallengeContext(ctx, nextFile, ch)
+		checkInterrupted(err)
+		if err != nil {
+			log.Fatalf("Failed to write the next challenge: %v\n", err)
+		}
+	}
+
+	return ch
+}
+
+// interruptContext returns a context that is canceled on the first SIGINT
+// or SIGTERM. A second one kills the process as usual.
+func interruptContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-c
+		signal.Stop(c)
+		log.Printf("Interrupted, stopping... (interrupt again to force)\n")
+		cancel()
+	}()
+	return ctx
+}
+
+// checkInterrupted exits if err is from a canceled interruptContext.
+func checkInterrupted(err error) {
+	if err == context.Canceled {
+		log.Fatalf("Interrupted. No partial output was left behind.\n")
+	}
+}
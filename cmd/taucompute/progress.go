@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/FiloSottile/powersoftau/powersoftau"
+)
+
+// progressReporter renders the progress of each step of the computation to
+// stderr, either as a progress bar with throughput and ETA, or as JSON
+// lines for when the output is collected by another program.
+type progressReporter struct {
+	out   io.Writer
+	json  bool
+	every time.Duration
+
+	step, unit string
+	start      time.Time
+	last       time.Time
+}
+
+// newProgressReporter returns a reporter for the -progress mode, or nil if
+// mode is "none". In "auto" mode, a bar is drawn if stderr is a terminal,
+// and JSON lines are printed otherwise.
+func newProgressReporter(mode string) (*progressReporter, error) {
+	switch mode {
+	case "none":
+		return nil, nil
+	case "auto":
+		fi, err := os.Stderr.Stat()
+		if err == nil && fi.Mode()&os.ModeCharDevice != 0 {
+			mode = "bar"
+		} else {
+			mode = "json"
+		}
+	}
+	switch mode {
+	case "bar":
+		return &progressReporter{out: os.Stderr, every: 200 * time.Millisecond}, nil
+	case "json":
+		return &progressReporter{out: os.Stderr, json: true, every: 10 * time.Second}, nil
+	}
+	return nil, fmt.Errorf("unknown progress mode %q", mode)
+}
+
+// Step returns the ProgressFunc for a new step, measured in unit, or nil
+// if p is nil.
+func (p *progressReporter) Step(step, unit string) powersoftau.ProgressFunc {
+	if p == nil {
+		return nil
+	}
+	p.step, p.unit = step, unit
+	p.start, p.last = time.Now(), time.Time{}
+	return p.report
+}
+
+func (p *progressReporter) report(done, total int64) {
+	now := time.Now()
+	if done != total && now.Sub(p.last) < p.every {
+		return
+	}
+	p.last = now
+
+	elapsed := now.Sub(p.start)
+	var rate float64
+	var eta time.Duration
+	if done > 0 && elapsed > 0 {
+		rate = float64(done) / elapsed.Seconds()
+		eta = time.Duration(float64(total-done) / rate * float64(time.Second))
+	}
+
+	if p.json {
+		line, _ := json.Marshal(struct {
+			Step       string  `json:"step"`
+			Done       int64   `json:"done"`
+			Total      int64   `json:"total"`
+			Unit       string  `json:"unit"`
+			Rate       float64 `json:"rate"`
+			ETASeconds float64 `json:"eta_seconds"`
+		}{p.step, done, total, p.unit, rate, eta.Seconds()})
+		fmt.Fprintf(p.out, "%s\n", line)
+		return
+	}
+
+	const width = 30
+	fraction := float64(done) / float64(total)
+	bar := strings.Repeat("=", int(fraction*width))
+	if len(bar) < width {
+		bar += ">" + strings.Repeat(" ", width-len(bar)-1)
+	}
+	status := fmt.Sprintf("ETA %v", eta.Round(time.Second))
+	if done == total {
+		status = fmt.Sprintf("took %v", elapsed.Round(time.Second))
+	}
+	fmt.Fprintf(p.out, "\r%s [%s] %5.1f%% %s %s\x1b[K", p.step, bar, fraction*100, formatRate(rate, p.unit), status)
+	if done == total {
+		fmt.Fprintf(p.out, "\n")
+	}
+}
+
+func formatRate(rate float64, unit string) string {
+	if unit == "bytes" {
+		return fmt.Sprintf("%.1f MB/s", rate/1e6)
+	}
+	return fmt.Sprintf("%.0f %s/s", rate, unit)
+}
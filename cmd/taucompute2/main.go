@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+
+	"github.com/FiloSottile/powersoftau/phase2"
+	"github.com/FiloSottile/powersoftau/powersoftau"
+)
+
+func main() {
+	inFile := flag.String("in", "./params", "path to the current phase 2 parameters")
+	outFile := flag.String("out", "./params.new", "path to the parameters with the new contribution")
+	checkSubgroup := flag.Bool("check-subgroup", false, "check that all points, and not only the ones the contribution multiplies, are in the prime order subgroup; slow")
+	flag.Parse()
+
+	log.Printf("Reading parameters...\n")
+	p, err := readParams(*inFile, *checkSubgroup)
+	if err != nil {
+		log.Fatalf("Failed to read the parameters: %v\n", err)
+	}
+
+	log.Printf("Computing contribution...\n")
+	hash := p.Contribute(runtime.NumCPU())
+
+	log.Printf("Writing parameters...\n")
+	if err := writeParams(*outFile, p); err != nil {
+		log.Fatalf("Failed to write the parameters: %v\n", err)
+	}
+
+	log.Printf("Done!\n\nYour contribution has been written to `%s`\n\nThe BLAKE2b hash of your contribution is:\n", *outFile)
+	for i := 0; i < 4; i++ {
+		fmt.Printf("\t")
+		for k := 0; k < 4; k++ {
+			fmt.Printf("%x ", hash[i*4*4+k*4:i*4*4+k*4+4])
+		}
+		fmt.Printf("\n")
+	}
+}
+
+func readParams(filename string, checkSubgroup bool) (*phase2.MPCParameters, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return phase2.ReadMPCParameters(bufio.NewReader(f), checkSubgroup)
+}
+
+// writeParams writes p to a temporary file and renames it to filename, so
+// that a failure never leaves partial parameters behind.
+func writeParams(filename string, p *phase2.MPCParameters) error {
+	return powersoftau.WriteFileAtomic(context.Background(), filename, p.WriteTo)
+}
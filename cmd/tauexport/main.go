@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"runtime"
+
+	"github.com/FiloSottile/powersoftau/powersoftau"
+	"github.com/FiloSottile/powersoftau/powersoftau/export"
+)
+
+func main() {
+	format := flag.String("format", "ptau", "output format: ptau, for snarkjs, or lagrange, for the powers in the Lagrange basis")
+	challengeFile := flag.String("challenge", "", "path to a challenge file to convert")
+	responseFile := flag.String("response", "", "path to a response file to convert, recorded as a contribution")
+	name := flag.String("name", "", "name of the contribution, with -response")
+	checkSubgroup := flag.Bool("check-subgroup", false, "check that all points are in the prime order subgroup; slow")
+	domain := flag.Uint("domain", 0, "base 2 logarithm of the Lagrange basis domain size, with -format lagrange; the number of powers if zero")
+	outFile := flag.String("out", "", "path to the output file (default \"./powersoftau.ptau\" or \"./lagrange\")")
+	flag.Parse()
+
+	if *format != "ptau" && *format != "lagrange" {
+		log.Fatalf("Unsupported -format %q\n", *format)
+	}
+	if *outFile == "" {
+		*outFile = "./powersoftau.ptau"
+		if *format == "lagrange" {
+			*outFile = "./lagrange"
+		}
+	}
+	if (*challengeFile == "") == (*responseFile == "") {
+		log.Fatalf("Exactly one of -challenge and -response is required\n")
+	}
+
+	p := &export.Ptau{}
+	if *challengeFile != "" {
+		log.Printf("Reading challenge...\n")
+		ch, err := powersoftau.ReadChallenge(*challengeFile, nil, *checkSubgroup)
+		if err != nil {
+			log.Fatalf("Failed to read the challenge: %v\n", err)
+		}
+		p.Parameters, p.Accumulator = ch.Parameters, ch.Accumulator
+	} else {
+		log.Printf("Reading response...\n")
+		resp, err := powersoftau.ReadResponse(*responseFile, nil, *checkSubgroup)
+		if err != nil {
+			log.Fatalf("Failed to read the response: %v\n", err)
+		}
+		p.Parameters, p.Accumulator = resp.Parameters, resp.Accumulator
+		c, err := export.NewContribution(resp)
+		if err != nil {
+			log.Fatalf("Failed to convert the response: %v\n", err)
+		}
+		c.Name = *name
+		p.Contributions = []*export.Contribution{c}
+	}
+
+	if *format == "lagrange" {
+		writeLagrange(*outFile, p.Accumulator, p.Parameters, *domain)
+		return
+	}
+
+	log.Printf("Writing %s...\n", *outFile)
+	if err := writePtau(*outFile, p); err != nil {
+		log.Fatalf("Failed to write the .ptau file: %v\n", err)
+	}
+
+	log.Printf("Done!\n\nThe 2^%d powers have been written to `%s`\n", p.Parameters.Power, *outFile)
+	if len(p.Contributions) > 0 {
+		log.Printf("The BLAKE2b hash of the next challenge is:\n")
+		hash := p.Contributions[0].NextChallenge
+		for i := 0; i < 4; i++ {
+			fmt.Printf("\t")
+			for k := 0; k < 4; k++ {
+				fmt.Printf("%x ", hash[i*4*4+k*4:i*4*4+k*4+4])
+			}
+			fmt.Printf("\n")
+		}
+	}
+}
+
+func writePtau(filename string, p *export.Ptau) error {
+	return powersoftau.WriteFileAtomic(context.Background(), filename, func(w io.Writer) error {
+		return export.WritePtau(w, p)
+	})
+}
+
+func writeLagrange(filename string, a *powersoftau.Accumulator, params *powersoftau.Parameters, domain uint) {
+	if domain == 0 {
+		domain = params.Power
+	}
+	if domain > params.Power {
+		log.Fatalf("Invalid -domain: the challenge only has 2^%d powers\n", params.Power)
+	}
+
+	log.Printf("Computing the Lagrange basis over 2^%d roots of unity...\n", domain)
+	g1, err := a.LagrangeG1(1<<domain, runtime.NumCPU())
+	if err != nil {
+		log.Fatalf("Failed to compute the Lagrange basis: %v\n", err)
+	}
+	g2, err := a.LagrangeG2(1<<domain, runtime.NumCPU())
+	if err != nil {
+		log.Fatalf("Failed to compute the Lagrange basis: %v\n", err)
+	}
+
+	log.Printf("Writing %s...\n", filename)
+	if err := powersoftau.WriteLagrange(filename, g1, g2, false); err != nil {
+		log.Fatalf("Failed to write the Lagrange basis: %v\n", err)
+	}
+	log.Printf("Done!\n\nThe 2^%d Lagrange basis points in G1 and G2 have been written uncompressed to `%s`\n", domain, filename)
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/FiloSottile/powersoftau/powersoftau"
+)
+
+func main() {
+	challengeFile := flag.String("challenge", "./challenge", "path to the new challenge file")
+	powers := flag.Uint("powers", powersoftau.DefaultPower, "base 2 logarithm of the number of powers of tau")
+	flag.Parse()
+
+	params, err := powersoftau.NewParameters(*powers)
+	if err != nil {
+		log.Fatalf("Invalid -powers: %v\n", err)
+	}
+
+	log.Printf("Writing a new challenge with 2^%d powers...\n", params.Power)
+	if err := powersoftau.WriteNewChallenge(*challengeFile, params); err != nil {
+		log.Fatalf("Failed to write the challenge: %v\n", err)
+	}
+
+	hash := powersoftau.NewChallengeHash(params)
+
+	log.Printf("Done!\n\nThe new challenge has been written to `%s`\n\nThe BLAKE2b hash of `%s` is:\n", *challengeFile, *challengeFile)
+	for i := 0; i < 4; i++ {
+		fmt.Printf("\t")
+		for k := 0; k < 4; k++ {
+			fmt.Printf("%x ", hash[i*4*4+k*4:i*4*4+k*4+4])
+		}
+		fmt.Printf("\n")
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+
+	"github.com/FiloSottile/powersoftau/phase2"
+	"github.com/FiloSottile/powersoftau/powersoftau"
+)
+
+func main() {
+	r1csFile := flag.String("r1cs", "./circuit.r1cs", "path to the circuit, in the iden3 .r1cs format")
+	challengeFile := flag.String("challenge", "", "path to the final challenge file of the Powers of Tau ceremony")
+	responseFile := flag.String("response", "", "path to the final response file of the Powers of Tau ceremony")
+	checkSubgroup := flag.Bool("check-subgroup", false, "check that all points are in the prime order subgroup; slow")
+	outFile := flag.String("out", "./params", "path to the initial phase 2 parameters")
+	flag.Parse()
+
+	if (*challengeFile == "") == (*responseFile == "") {
+		log.Fatalf("Exactly one of -challenge and -response is required\n")
+	}
+
+	log.Printf("Reading circuit...\n")
+	cs, err := readR1CS(*r1csFile)
+	if err != nil {
+		log.Fatalf("Failed to read the circuit: %v\n", err)
+	}
+	log.Printf("The circuit has %d constraints and %d variables\n", len(cs.Constraints), cs.NumVariables())
+
+	var ch *powersoftau.Challenge
+	if *challengeFile != "" {
+		log.Printf("Reading challenge...\n")
+		ch, err = powersoftau.ReadChallenge(*challengeFile, nil, *checkSubgroup)
+		if err != nil {
+			log.Fatalf("Failed to read the challenge: %v\n", err)
+		}
+	} else {
+		log.Printf("Reading response...\n")
+		ch, err = powersoftau.ReadResponse(*responseFile, nil, *checkSubgroup)
+		if err != nil {
+			log.Fatalf("Failed to read the response: %v\n", err)
+		}
+	}
+
+	log.Printf("Computing the initial parameters...\n")
+	p, err := phase2.NewMPCParameters(ch.Accumulator, cs, runtime.NumCPU())
+	if err != nil {
+		log.Fatalf("Failed to compute the parameters: %v\n", err)
+	}
+
+	log.Printf("Writing parameters...\n")
+	if err := writeParams(*outFile, p); err != nil {
+		log.Fatalf("Failed to write the parameters: %v\n", err)
+	}
+
+	log.Printf("Done!\n\nThe initial parameters have been written to `%s`\n\nThe BLAKE2b hash of the initial parameters is:\n", *outFile)
+	for i := 0; i < 4; i++ {
+		fmt.Printf("\t")
+		for k := 0; k < 4; k++ {
+			fmt.Printf("%x ", p.CSHash[i*4*4+k*4:i*4*4+k*4+4])
+		}
+		fmt.Printf("\n")
+	}
+}
+
+func readR1CS(filename string) (*phase2.R1CS, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	cs, _, err := phase2.ReadR1CS(f)
+	return cs, err
+}
+
+// writeParams writes p to a temporary file and renames it to filename, so
+// that a failure never leaves partial parameters behind.
+func writeParams(filename string, p *phase2.MPCParameters) error {
+	return powersoftau.WriteFileAtomic(context.Background(), filename, p.WriteTo)
+}
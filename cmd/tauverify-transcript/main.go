@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/FiloSottile/powersoftau/powersoftau"
+)
+
+func main() {
+	challengeFile := flag.String("challenge", "./challenge", "path to the initial challenge file")
+	responsesDir := flag.String("responses", "", "path to a directory of response files, verified in the order of the last number in their names")
+	manifestFile := flag.String("manifest", "", "path to a file listing the response files in order, one per line, relative to it")
+	powers := flag.Uint("powers", 0, "base 2 logarithm of the number of powers of tau; detected from the challenge size if zero")
+	checkSubgroup := flag.Bool("check-subgroup", true, "check that all points are in the prime order subgroup")
+	flag.Parse()
+
+	if (*responsesDir == "") == (*manifestFile == "") {
+		log.Fatalf("Exactly one of -responses and -manifest is required\n")
+	}
+	var responses []string
+	var err error
+	if *responsesDir != "" {
+		responses, err = listDir(*responsesDir)
+	} else {
+		responses, err = readManifest(*manifestFile)
+	}
+	if err != nil {
+		log.Fatalf("Failed to list the responses: %v\n", err)
+	}
+	if len(responses) == 0 {
+		log.Fatalf("No responses to verify\n")
+	}
+
+	opts := &powersoftau.TranscriptOptions{
+		Processes:     runtime.NumCPU(),
+		CheckSubgroup: *checkSubgroup,
+	}
+	if *powers != 0 {
+		opts.Params, err = powersoftau.NewParameters(*powers)
+		if err != nil {
+			log.Fatalf("Invalid -powers: %v\n", err)
+		}
+	}
+
+	log.Printf("Reading challenge...\n")
+	t, err := powersoftau.NewTranscript(*challengeFile, opts)
+	if err != nil {
+		log.Fatalf("Failed to read the challenge: %v\n", err)
+	}
+	log.Printf("The BLAKE2b hash of `%s` is:\n", *challengeFile)
+	printHash(t.ChallengeHash)
+	if bytes.Equal(t.ChallengeHash, powersoftau.NewChallengeHash(t.Parameters())) {
+		log.Printf("It's the initial challenge of a ceremony with 2^%d powers, as made by taunew.\n", t.Parameters().Power)
+	} else {
+		log.Printf("WARNING: it's not the initial challenge of a ceremony, it's trusted as a starting point.\n")
+	}
+
+	for i, response := range responses {
+		log.Printf("Verifying contribution #%d `%s`...\n", i+1, response)
+		c, err := t.Verify(response)
+		if err != nil {
+			log.Fatalf("Contribution #%d `%s` is INVALID: %v\n", i+1, response, err)
+		}
+		log.Printf("Contribution #%d is valid. The BLAKE2b hash of `%s` is:\n", i+1, response)
+		printHash(c.ResponseHash)
+	}
+
+	log.Printf("Done!\n\nAll %d contributions are valid. The BLAKE2b hash of the final challenge is:\n", t.Contributions)
+	printHash(t.ChallengeHash)
+}
+
+func printHash(h []byte) {
+	for i := 0; i < 4; i++ {
+		fmt.Printf("\t")
+		for k := 0; k < 4; k++ {
+			fmt.Printf("%x ", h[i*4*4+k*4:i*4*4+k*4+4])
+		}
+		fmt.Printf("\n")
+	}
+}
+
+// listDir returns the regular files in dir, skipping hidden ones, sorted by
+// the last number in their name, so that response10 comes after response9.
+// Names without a number, or with the same number, are rejected as their
+// order would be ambiguous.
+func listDir(dir string) ([]string, error) {
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	type response struct{ path, n string }
+	var responses []response
+	seen := make(map[string]string)
+	for _, fi := range fis {
+		if !fi.Mode().IsRegular() || strings.HasPrefix(fi.Name(), ".") {
+			continue
+		}
+		m := lastNumber.FindStringSubmatch(fi.Name())
+		if m == nil {
+			return nil, fmt.Errorf("%s has no number in its name; use -manifest to list the responses in order", fi.Name())
+		}
+		n := strings.TrimLeft(m[1], "0")
+		if other, ok := seen[n]; ok {
+			return nil, fmt.Errorf("%s and %s have the same number; use -manifest to list the responses in order", other, fi.Name())
+		}
+		seen[n] = fi.Name()
+		responses = append(responses, response{filepath.Join(dir, fi.Name()), n})
+	}
+	// Without leading zeroes, a shorter number is a smaller one.
+	sort.Slice(responses, func(i, j int) bool {
+		a, b := responses[i].n, responses[j].n
+		if len(a) != len(b) {
+			return len(a) < len(b)
+		}
+		return a < b
+	})
+	var res []string
+	for _, r := range responses {
+		res = append(res, r.path)
+	}
+	return res, nil
+}
+
+var lastNumber = regexp.MustCompile(`([0-9]+)[^0-9]*$`)
+
+// readManifest returns the paths listed in the manifest, skipping empty
+// lines and lines starting with #. Relative paths are resolved from the
+// directory of the manifest.
+func readManifest(filename string) ([]string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var res []string
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !filepath.IsAbs(line) {
+			line = filepath.Join(filepath.Dir(filename), line)
+		}
+		res = append(res, line)
+	}
+	return res, s.Err()
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"runtime"
+
+	"github.com/FiloSottile/powersoftau/powersoftau"
+)
+
+func main() {
+	challengeFile := flag.String("challenge", "./challenge", "path to the challenge file")
+	responseFile := flag.String("response", "./response", "path to the response file")
+	checkSubgroup := flag.Bool("check-subgroup", true, "check that all points are in the prime order subgroup")
+	flag.Parse()
+
+	log.Printf("Reading challenge...\n")
+	ch, err := powersoftau.ReadChallenge(*challengeFile, nil, *checkSubgroup)
+	if err != nil {
+		log.Fatalf("Failed to read the challenge: %v\n", err)
+	}
+
+	log.Printf("Reading response...\n")
+	resp, err := powersoftau.ReadResponse(*responseFile, nil, *checkSubgroup)
+	if err != nil {
+		log.Fatalf("Failed to read the response: %v\n", err)
+	}
+
+	log.Printf("Verifying response...\n")
+	if err := ch.Verify(resp, runtime.NumCPU()); err != nil {
+		log.Fatalf("The response is INVALID: %v\n", err)
+	}
+
+	log.Printf("Done!\n\nThe response `%s` is a valid contribution to `%s`\n\nThe BLAKE2b hash of `%s` is:\n", *responseFile, *challengeFile, *responseFile)
+	for i := 0; i < 4; i++ {
+		fmt.Printf("\t")
+		for k := 0; k < 4; k++ {
+			fmt.Printf("%x ", resp.ResponseHash[i*4*4+k*4:i*4*4+k*4+4])
+		}
+		fmt.Printf("\n")
+	}
+}
@@ -0,0 +1,209 @@
+package phase2
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/FiloSottile/powersoftau/bls12"
+	"github.com/FiloSottile/powersoftau/powersoftau"
+	"golang.org/x/crypto/blake2b"
+)
+
+/*
+
+A contribution multiplies δ by a secret d, like in ebfull/phase2:
+
+	1. Pick d and a random G1 point S [keypair]
+
+	2. Hash the hash of the initial parameters, the public keys of the
+	   previous contributions, S and d*S into the transcript
+
+	3. Map the transcript to a G2 point R with HashToG2, and compute d*R, so
+	   that the ratio of (S, d*S) and (R, d*R) proves knowledge of d
+
+	4. Multiply DeltaG1 and DeltaG2 by d, and H and L by 1/d [contribute]
+
+Verification checks each proof of knowledge, that each DeltaAfter is the
+previous one multiplied by the proven d, and that the final H and L are the
+initial ones divided by the final δ.
+
+*/
+
+// A PublicKey proves knowledge of the d of a contribution, and binds it to
+// the circuit and to the previous contributions.
+type PublicKey struct {
+	// DeltaAfter is DeltaG1 after the contribution.
+	DeltaAfter *bls12.EP
+
+	S, SDelta  *bls12.EP
+	RDelta     *bls12.EP2
+	Transcript []byte
+}
+
+// Contribute multiplies δ by a new random secret, and appends the public
+// key proving it to p.Contributions, using processes goroutines. It returns
+// the BLAKE2b hash of the public key, which the contributor should publish.
+func (p *MPCParameters) Contribute(processes int) []byte {
+	return p.contributeWith(processes, powersoftau.RandomScalar)
+}
+
+// contributeWith is like Contribute, but draws d and then the scalar of S
+// from randomScalar.
+func (p *MPCParameters) contributeWith(processes int, randomScalar func() *bls12.Fr) []byte {
+	delta := randomScalar()
+	defer delta.SetZero()
+	s := randomScalar()
+	defer s.SetZero()
+
+	var dInv bls12.Fr
+	defer dInv.SetZero()
+	var dBuf, dInvBuf, sBuf [32]byte
+	defer powersoftau.ZeroBytes(dBuf[:])
+	defer powersoftau.ZeroBytes(dInvBuf[:])
+	defer powersoftau.ZeroBytes(sBuf[:])
+	d := delta.Bytes(&dBuf)
+	dInvBytes := dInv.Set(delta).Inverse().Bytes(&dInvBuf)
+
+	key := &PublicKey{S: (&bls12.EP{}).SetOne().ScalarMultConstantTime(s.Bytes(&sBuf))}
+	key.SDelta = key.S.Copy().ScalarMultConstantTime(d)
+	key.Transcript = p.transcript(key.S, key.SDelta)
+	key.RDelta = powersoftau.HashToG2(key.Transcript).ScalarMultConstantTime(d)
+
+	params := p.Params
+	params.DeltaG1.ScalarMultConstantTime(d)
+	params.DeltaG2.ScalarMultConstantTime(d)
+	key.DeltaAfter = params.DeltaG1.Copy()
+	for _, v := range [][]*bls12.EP{params.H, params.L} {
+		v := v
+		powersoftau.Parallelize(len(v), processes, func(a, b int) {
+			for i := a; i < b; i++ {
+				v[i].ScalarMultConstantTime(dInvBytes)
+			}
+			bls12.NormalizeBatchG1(v[a:b])
+		})
+	}
+
+	p.Contributions = append(p.Contributions, key)
+	h, _ := blake2b.New512(nil)
+	key.WriteTo(h)
+	return h.Sum(nil)
+}
+
+// transcript returns the hash that the proof of knowledge of the next
+// contribution, with points S and SDelta, is bound to.
+func (p *MPCParameters) transcript(S, SDelta *bls12.EP) []byte {
+	h, _ := blake2b.New512(nil)
+	h.Write(p.CSHash)
+	for _, c := range p.Contributions {
+		c.WriteTo(h)
+	}
+	h.Write(S.EncodeUncompressed())
+	h.Write(SDelta.EncodeUncompressed())
+	return h.Sum(nil)
+}
+
+// Verify checks that p is the result of valid contributions on top of the
+// initial parameters, as returned by NewMPCParameters for the same circuit
+// and accumulator, using processes goroutines. It returns the hash of each
+// contribution, like Contribute.
+func (p *MPCParameters) Verify(initial *MPCParameters, processes int) ([][]byte, error) {
+	if len(initial.Contributions) != 0 {
+		return nil, errors.New("the initial parameters have contributions")
+	}
+	if !bytes.Equal(p.CSHash, initial.CSHash) {
+		return nil, errors.New("the parameters are for a different circuit")
+	}
+	before, after := initial.Params, p.Params
+	if err := checkFixed(before, after); err != nil {
+		return nil, err
+	}
+
+	var hashes [][]byte
+	delta := before.DeltaG1
+	chain := &MPCParameters{CSHash: p.CSHash}
+	for i, key := range p.Contributions {
+		if key.S.IsZero() || key.SDelta.IsZero() || key.RDelta.IsZero() || key.DeltaAfter.IsZero() {
+			return nil, fmt.Errorf("contribution %d contains a point at infinity", i)
+		}
+		if !bytes.Equal(key.Transcript, chain.transcript(key.S, key.SDelta)) {
+			return nil, fmt.Errorf("contribution %d has the wrong transcript", i)
+		}
+		r := powersoftau.HashToG2(key.Transcript)
+		ok := powersoftau.SameRatio(key.S, key.SDelta, r, key.RDelta) && powersoftau.SameRatio(delta, key.DeltaAfter, r, key.RDelta)
+		r.Close()
+		if !ok {
+			return nil, fmt.Errorf("the proof of knowledge of contribution %d is invalid", i)
+		}
+		delta = key.DeltaAfter
+		chain.Contributions = append(chain.Contributions, key)
+
+		h, _ := blake2b.New512(nil)
+		key.WriteTo(h)
+		hashes = append(hashes, h.Sum(nil))
+	}
+
+	if !after.DeltaG1.Equal(delta) {
+		return nil, errors.New("DeltaG1 does not match the last contribution")
+	}
+	g1 := (&bls12.EP{}).SetOne()
+	g2 := bls12.NewEP2().SetOne()
+	defer g2.Close()
+	if !powersoftau.SameRatio(g1, after.DeltaG1, g2, after.DeltaG2) {
+		return nil, errors.New("DeltaG2 is not consistent with DeltaG1")
+	}
+	// H and L were divided by δ, so H[i] / H_initial[i] == 1 / δ.
+	if s, sx := mergePairs(after.H, before.H, processes); !powersoftau.SameRatio(s, sx, g2, after.DeltaG2) {
+		return nil, errors.New("H was not updated with δ")
+	}
+	if s, sx := mergePairs(after.L, before.L, processes); !powersoftau.SameRatio(s, sx, g2, after.DeltaG2) {
+		return nil, errors.New("L was not updated with δ")
+	}
+	return hashes, nil
+}
+
+// checkFixed checks that the parts of the parameters that contributions
+// don't change are the same in before and after.
+func checkFixed(before, after *Parameters) error {
+	if len(after.H) != len(before.H) || len(after.L) != len(before.L) {
+		return errors.New("the parameters have the wrong size")
+	}
+	if !after.AlphaG1.Equal(before.AlphaG1) || !after.BetaG1.Equal(before.BetaG1) ||
+		!after.BetaG2.Equal(before.BetaG2) || !after.GammaG2.Equal(before.GammaG2) {
+		return errors.New("the verifying key was changed")
+	}
+	for _, s := range []struct {
+		name          string
+		before, after []*bls12.EP
+	}{{"IC", before.IC, after.IC}, {"A", before.A, after.A}, {"BG1", before.BG1, after.BG1}} {
+		if len(s.after) != len(s.before) {
+			return fmt.Errorf("%s was changed", s.name)
+		}
+		for i := range s.before {
+			if !s.after[i].Equal(s.before[i]) {
+				return fmt.Errorf("%s was changed", s.name)
+			}
+		}
+	}
+	if len(after.BG2) != len(before.BG2) {
+		return errors.New("BG2 was changed")
+	}
+	for i := range before.BG2 {
+		if !after.BG2[i].Equal(before.BG2[i]) {
+			return errors.New("BG2 was changed")
+		}
+	}
+	return nil
+}
+
+// mergePairs returns a random linear combination of a, and the combination
+// of b with the same coefficients, so that if a[i]/b[i] is the same for all
+// i, that is also the ratio of the two results.
+func mergePairs(a, b []*bls12.EP, processes int) (*bls12.EP, *bls12.EP) {
+	scalars := make([][]byte, len(a))
+	for i := range scalars {
+		scalars[i] = powersoftau.RandomScalar().Bytes(new([32]byte))
+	}
+	m := &bls12.MultiExp{Workers: processes}
+	return m.G1(a, scalars), m.G1(b, scalars)
+}
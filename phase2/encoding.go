@@ -0,0 +1,272 @@
+package phase2
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/FiloSottile/powersoftau/bls12"
+	"github.com/FiloSottile/powersoftau/powersoftau"
+	"golang.org/x/crypto/blake2b"
+)
+
+// WriteTo writes p like bellman writes Groth16 parameters: the verifying
+// key points, then the IC, H, L, A, BG1 and BG2 vectors each prefixed by
+// its big-endian 32-bit length. Points are uncompressed.
+func (p *Parameters) WriteTo(w io.Writer) error {
+	for _, point := range [][]byte{
+		p.AlphaG1.EncodeUncompressed(),
+		p.BetaG1.EncodeUncompressed(),
+		p.BetaG2.EncodeUncompressed(),
+		p.GammaG2.EncodeUncompressed(),
+		p.DeltaG1.EncodeUncompressed(),
+		p.DeltaG2.EncodeUncompressed(),
+	} {
+		if _, err := w.Write(point); err != nil {
+			return err
+		}
+	}
+	for _, s := range [][]*bls12.EP{p.IC, p.H, p.L, p.A, p.BG1} {
+		if err := writeG1s(w, s); err != nil {
+			return err
+		}
+	}
+	return writeG2s(w, p.BG2)
+}
+
+// ReadParameters reads parameters written by Parameters.WriteTo. If
+// checkSubgroup is true, all points are checked to be in the prime order
+// subgroup. DeltaG1, DeltaG2, H and L are always checked, since Contribute
+// multiplies them with ScalarMultConstantTime, which is only correct there.
+func ReadParameters(r io.Reader, checkSubgroup bool) (*Parameters, error) {
+	g1, err := readG1s(r, "verifying key", 2, checkSubgroup)
+	if err != nil {
+		return nil, err
+	}
+	g2, err := readG2s(r, "verifying key", 2, checkSubgroup)
+	if err != nil {
+		return nil, err
+	}
+	delta, err := readG1s(r, "verifying key", 1, true)
+	if err != nil {
+		closeG2s(g2)
+		return nil, err
+	}
+	deltaG2, err := readG2s(r, "verifying key", 1, true)
+	if err != nil {
+		closeG2s(g2)
+		return nil, err
+	}
+	p := &Parameters{
+		AlphaG1: g1[0], BetaG1: g1[1],
+		BetaG2: g2[0], GammaG2: g2[1],
+		DeltaG1: delta[0], DeltaG2: deltaG2[0],
+	}
+	for _, s := range []struct {
+		name       string
+		dst        *[]*bls12.EP
+		contribute bool
+	}{{"IC", &p.IC, false}, {"H", &p.H, true}, {"L", &p.L, true}, {"A", &p.A, false}, {"BG1", &p.BG1, false}} {
+		n, err := readLength(r)
+		if err != nil {
+			p.close()
+			return nil, err
+		}
+		if *s.dst, err = readG1s(r, s.name, n, checkSubgroup || s.contribute); err != nil {
+			p.close()
+			return nil, err
+		}
+	}
+	n, err := readLength(r)
+	if err != nil {
+		p.close()
+		return nil, err
+	}
+	if p.BG2, err = readG2s(r, "BG2", n, checkSubgroup); err != nil {
+		p.close()
+		return nil, err
+	}
+	return p, nil
+}
+
+// close frees the G2 points of parameters that are being discarded.
+func (p *Parameters) close() {
+	for _, q := range []*bls12.EP2{p.BetaG2, p.GammaG2, p.DeltaG2} {
+		q.Close()
+	}
+	closeG2s(p.BG2)
+}
+
+// WriteTo writes p like ebfull/phase2: the parameters, the hash of the
+// initial parameters, and the length-prefixed contributions.
+func (p *MPCParameters) WriteTo(w io.Writer) error {
+	if err := p.Params.WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := w.Write(p.CSHash); err != nil {
+		return err
+	}
+	if err := writeLength(w, len(p.Contributions)); err != nil {
+		return err
+	}
+	for _, c := range p.Contributions {
+		if err := c.WriteTo(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadMPCParameters reads parameters written by MPCParameters.WriteTo.
+// checkSubgroup works like for ReadParameters.
+func ReadMPCParameters(r io.Reader, checkSubgroup bool) (*MPCParameters, error) {
+	params, err := ReadParameters(r, checkSubgroup)
+	if err != nil {
+		return nil, err
+	}
+	p := &MPCParameters{Params: params, CSHash: make([]byte, blake2b.Size)}
+	if _, err := io.ReadFull(r, p.CSHash); err != nil {
+		params.close()
+		return nil, err
+	}
+	n, err := readLength(r)
+	if err != nil {
+		params.close()
+		return nil, err
+	}
+	for i := 0; i < n; i++ {
+		c, err := ReadPublicKey(r, checkSubgroup)
+		if err != nil {
+			params.close()
+			for _, c := range p.Contributions {
+				c.RDelta.Close()
+			}
+			return nil, fmt.Errorf("invalid contribution %d: %v", i, err)
+		}
+		p.Contributions = append(p.Contributions, c)
+	}
+	return p, nil
+}
+
+// WriteTo writes the public key: DeltaAfter, S and SDelta, RDelta, all
+// uncompressed, and the transcript hash.
+func (k *PublicKey) WriteTo(w io.Writer) error {
+	for _, b := range [][]byte{
+		k.DeltaAfter.EncodeUncompressed(),
+		k.S.EncodeUncompressed(),
+		k.SDelta.EncodeUncompressed(),
+		k.RDelta.EncodeUncompressed(),
+		k.Transcript,
+	} {
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadPublicKey reads a public key written by PublicKey.WriteTo.
+func ReadPublicKey(r io.Reader, checkSubgroup bool) (*PublicKey, error) {
+	g1, err := readG1s(r, "PublicKey", 3, checkSubgroup)
+	if err != nil {
+		return nil, err
+	}
+	g2, err := readG2s(r, "PublicKey", 1, checkSubgroup)
+	if err != nil {
+		return nil, err
+	}
+	k := &PublicKey{
+		DeltaAfter: g1[0], S: g1[1], SDelta: g1[2], RDelta: g2[0],
+		Transcript: make([]byte, blake2b.Size),
+	}
+	if _, err := io.ReadFull(r, k.Transcript); err != nil {
+		k.RDelta.Close()
+		return nil, err
+	}
+	return k, nil
+}
+
+func writeLength(w io.Writer, n int) error {
+	if n > 1<<32-1 {
+		return errors.New("too many points")
+	}
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], uint32(n))
+	_, err := w.Write(buf[:])
+	return err
+}
+
+func readLength(r io.Reader) (int, error) {
+	var buf [4]byte
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		return 0, err
+	}
+	return int(binary.BigEndian.Uint32(buf[:])), nil
+}
+
+// writeG1s writes the length of s, and its points normalized in batches.
+func writeG1s(w io.Writer, s []*bls12.EP) error {
+	if err := writeLength(w, len(s)); err != nil {
+		return err
+	}
+	for i, p := range s {
+		if i%(1<<10) == 0 {
+			bls12.NormalizeBatchG1(s[i:powersoftau.ChunkEnd(i, len(s))])
+		}
+		if _, err := w.Write(p.EncodeUncompressed()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeG2s(w io.Writer, s []*bls12.EP2) error {
+	if err := writeLength(w, len(s)); err != nil {
+		return err
+	}
+	for i, p := range s {
+		if i%(1<<10) == 0 {
+			bls12.NormalizeBatchG2(s[i:powersoftau.ChunkEnd(i, len(s))])
+		}
+		if _, err := w.Write(p.EncodeUncompressed()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readG1s reads n uncompressed points. name is used to identify the
+// offending point in errors.
+func readG1s(r io.Reader, name string, n int, checkSubgroup bool) ([]*bls12.EP, error) {
+	var res []*bls12.EP
+	for i := 0; i < n; i++ {
+		p, err := powersoftau.ReadG1(r, checkSubgroup)
+		if err != nil {
+			return nil, fmt.Errorf("%s[%d]: %v", name, i, err)
+		}
+		res = append(res, p)
+	}
+	return res, nil
+}
+
+// readG2s is like readG1s, but for G2. On error, it frees the points it
+// already read.
+func readG2s(r io.Reader, name string, n int, checkSubgroup bool) ([]*bls12.EP2, error) {
+	var res []*bls12.EP2
+	for i := 0; i < n; i++ {
+		p, err := powersoftau.ReadG2(r, checkSubgroup)
+		if err != nil {
+			closeG2s(res)
+			return nil, fmt.Errorf("%s[%d]: %v", name, i, err)
+		}
+		res = append(res, p)
+	}
+	return res, nil
+}
+
+func closeG2s(s []*bls12.EP2) {
+	for _, p := range s {
+		p.Close()
+	}
+}
@@ -0,0 +1,214 @@
+// Package phase2 implements the circuit-specific second phase of the Groth16
+// setup, where participants take turns multiplying a secret delta into the
+// parameters derived from the Powers of Tau accumulator and a circuit.
+package phase2
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/FiloSottile/powersoftau/bls12"
+	"github.com/FiloSottile/powersoftau/powersoftau"
+	"golang.org/x/crypto/blake2b"
+)
+
+/*
+
+The initial parameters are those of a Groth16 setup with γ = δ = 1, like in
+ebfull/phase2, computed from the accumulator instead of from the secrets:
+
+	1. Pick the domain size m, the smallest power of two that fits the
+	   constraints plus one constraint x * 0 = 0 for each input x, which
+	   makes the IC query independent [bellman generate_parameters]
+
+	2. Compute the Lagrange basis L_j(τ) of the accumulator over the m-th
+	   roots of unity, in G1 and G2, and of α and β times it in G1
+
+	3. For each variable i, evaluate at τ the polynomials A_i, B_i, C_i
+	   which interpolate its coefficients in the constraints, as
+	   A_i(τ) = Σ_j A_ij L_j(τ)
+		3.1. A[i] = A_i(τ) * G1, BG1[i] = B_i(τ) * G1, BG2[i] = B_i(τ) * G2
+		3.2. IC[i] for the inputs and L[i] for the private variables are
+		     (β A_i(τ) + α B_i(τ) + C_i(τ)) * G1, divided by γ and δ
+
+	4. H[i] = τ^i (τ^m - 1) * G1 / δ for i < m - 1, from the powers of τ
+
+	5. Drop the points at infinity from A, BG1 and BG2, which the prover
+	   skips, and fail if any of IC and L is at infinity, meaning a
+	   variable is unconstrained [phase2 MPCParameters::new]
+
+The hash of the initial parameters binds all contributions to the circuit.
+
+*/
+
+// Parameters are Groth16 proving parameters, named like bellman's.
+type Parameters struct {
+	AlphaG1 *bls12.EP
+	BetaG1  *bls12.EP
+	BetaG2  *bls12.EP2
+	GammaG2 *bls12.EP2
+	DeltaG1 *bls12.EP
+	DeltaG2 *bls12.EP2
+	IC      []*bls12.EP
+
+	H   []*bls12.EP
+	L   []*bls12.EP
+	A   []*bls12.EP
+	BG1 []*bls12.EP
+	BG2 []*bls12.EP2
+}
+
+// MPCParameters are the state of a phase 2 ceremony: the current parameters,
+// the hash of the initial ones, and the public keys of the contributions.
+type MPCParameters struct {
+	Params        *Parameters
+	CSHash        []byte
+	Contributions []*PublicKey
+}
+
+// NewMPCParameters returns the initial parameters for the circuit cs, from
+// the accumulator of a completed Powers of Tau ceremony, using processes
+// goroutines.
+func NewMPCParameters(a *powersoftau.Accumulator, cs *R1CS, processes int) (*MPCParameters, error) {
+	if err := cs.check(); err != nil {
+		return nil, err
+	}
+	numInputs := 1 + cs.NumPublic
+	m := 1
+	for m < len(cs.Constraints)+numInputs {
+		m <<= 1
+	}
+	if m > len(a.TauG2) {
+		return nil, fmt.Errorf("the circuit needs %d powers of tau, the accumulator has %d", m, len(a.TauG2))
+	}
+
+	tauG1, err := a.LagrangeG1(m, processes)
+	if err != nil {
+		return nil, err
+	}
+	alphaG1, err := a.LagrangeAlphaG1(m, processes)
+	if err != nil {
+		return nil, err
+	}
+	betaG1, err := a.LagrangeBetaG1(m, processes)
+	if err != nil {
+		return nil, err
+	}
+	tauG2, err := a.LagrangeG2(m, processes)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		for _, p := range tauG2 {
+			p.Close()
+		}
+	}()
+
+	// Transpose the constraints into the coefficients of each variable.
+	n := cs.NumVariables()
+	at, bt, ct := make([][]basisTerm, n), make([][]basisTerm, n), make([][]basisTerm, n)
+	for j, c := range cs.Constraints {
+		for _, t := range c.A {
+			at[t.Variable] = append(at[t.Variable], basisTerm{j, t.Coeff})
+		}
+		for _, t := range c.B {
+			bt[t.Variable] = append(bt[t.Variable], basisTerm{j, t.Coeff})
+		}
+		for _, t := range c.C {
+			ct[t.Variable] = append(ct[t.Variable], basisTerm{j, t.Coeff})
+		}
+	}
+	for i := 0; i < numInputs; i++ {
+		at[i] = append(at[i], basisTerm{len(cs.Constraints) + i, big.NewInt(1)})
+	}
+
+	aG1, bG1, ext := make([]*bls12.EP, n), make([]*bls12.EP, n), make([]*bls12.EP, n)
+	bG2 := make([]*bls12.EP2, n)
+	powersoftau.Parallelize(n, processes, func(x, y int) {
+		for i := x; i < y; i++ {
+			aG1[i] = evalG1(at[i], tauG1)
+			bG1[i] = evalG1(bt[i], tauG1)
+			bG2[i] = evalG2(bt[i], tauG2)
+			ext[i] = evalG1(at[i], betaG1).Add(evalG1(bt[i], alphaG1)).Add(evalG1(ct[i], tauG1))
+		}
+	})
+	for i, p := range ext {
+		if p.IsZero() {
+			closeG2s(bG2)
+			return nil, fmt.Errorf("variable %d is unconstrained", i)
+		}
+	}
+
+	params := &Parameters{
+		AlphaG1: a.AlphaTau[0].Copy(),
+		BetaG1:  a.BetaTau[0].Copy(),
+		BetaG2:  a.BetaG2.Copy(),
+		GammaG2: bls12.NewEP2().SetOne(),
+		DeltaG1: (&bls12.EP{}).SetOne(),
+		DeltaG2: bls12.NewEP2().SetOne(),
+		IC:      ext[:numInputs],
+		L:       ext[numInputs:],
+		H:       make([]*bls12.EP, m-1),
+	}
+	powersoftau.Parallelize(m-1, processes, func(x, y int) {
+		for i := x; i < y; i++ {
+			params.H[i] = a.TauG1[i+m].Copy().Add(a.TauG1[i].Copy().Neg())
+		}
+	})
+	for i := range aG1 {
+		if !aG1[i].IsZero() {
+			params.A = append(params.A, aG1[i])
+		}
+		if !bG1[i].IsZero() {
+			params.BG1 = append(params.BG1, bG1[i])
+		}
+		if !bG2[i].IsZero() {
+			params.BG2 = append(params.BG2, bG2[i])
+		} else {
+			bG2[i].Close()
+		}
+	}
+
+	h, _ := blake2b.New512(nil)
+	if err := params.WriteTo(h); err != nil {
+		return nil, err
+	}
+	return &MPCParameters{Params: params, CSHash: h.Sum(nil)}, nil
+}
+
+// A basisTerm is the coefficient of a variable in a constraint, which
+// multiplies the Lagrange basis element of the constraint.
+type basisTerm struct {
+	constraint int
+	coeff      *big.Int
+}
+
+func evalG1(terms []basisTerm, basis []*bls12.EP) *bls12.EP {
+	sum := (&bls12.EP{}).SetZero()
+	for _, t := range terms {
+		switch {
+		case t.coeff.Sign() == 0:
+		case t.coeff.IsInt64() && t.coeff.Int64() == 1:
+			sum.Add(basis[t.constraint])
+		default:
+			sum.Add(basis[t.constraint].Copy().ScalarMult(t.coeff.Bytes()))
+		}
+	}
+	return sum
+}
+
+func evalG2(terms []basisTerm, basis []*bls12.EP2) *bls12.EP2 {
+	sum := bls12.NewEP2().SetZero()
+	for _, t := range terms {
+		switch {
+		case t.coeff.Sign() == 0:
+		case t.coeff.IsInt64() && t.coeff.Int64() == 1:
+			sum.Add(basis[t.constraint])
+		default:
+			p := basis[t.constraint].Copy().ScalarMult(t.coeff.Bytes())
+			sum.Add(p)
+			p.Close()
+		}
+	}
+	return sum
+}
@@ -0,0 +1,269 @@
+package phase2
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/FiloSottile/powersoftau/bls12"
+	"github.com/FiloSottile/powersoftau/powersoftau"
+)
+
+var order = (&big.Int{}).SetBytes(bls12.ScalarOrder())
+
+// testAccumulator returns an accumulator with 8 powers of τ = 3, α = 5,
+// β = 7.
+func testAccumulator() *powersoftau.Accumulator {
+	tau, alpha, beta := big.NewInt(3), big.NewInt(5), big.NewInt(7)
+	a := &powersoftau.Accumulator{BetaG2: bls12.NewEP2().ScalarBaseMult(beta.Bytes())}
+	k := big.NewInt(1)
+	for i := 0; i < 2*8-1; i++ {
+		a.TauG1 = append(a.TauG1, (&bls12.EP{}).ScalarBaseMult(k.Bytes()))
+		if i < 8 {
+			a.TauG2 = append(a.TauG2, bls12.NewEP2().ScalarBaseMult(k.Bytes()))
+			ak := (&big.Int{}).Mul(k, alpha)
+			a.AlphaTau = append(a.AlphaTau, (&bls12.EP{}).ScalarBaseMult(ak.Mod(ak, order).Bytes()))
+			bk := (&big.Int{}).Mul(k, beta)
+			a.BetaTau = append(a.BetaTau, (&bls12.EP{}).ScalarBaseMult(bk.Mod(bk, order).Bytes()))
+		}
+		k.Mul(k, tau).Mod(k, order)
+	}
+	return a
+}
+
+// testCircuit returns the circuit x * x = y, with public y and private x.
+func testCircuit() *R1CS {
+	one := big.NewInt(1)
+	return &R1CS{
+		NumPublic:  1,
+		NumPrivate: 1,
+		Constraints: []Constraint{{
+			A: LinearCombination{{2, one}},
+			B: LinearCombination{{2, one}},
+			C: LinearCombination{{1, one}},
+		}},
+	}
+}
+
+// prove returns a Groth16 proof with r = s = 0 for the witness of
+// testCircuit with x = 3, and the verifier's input term.
+func prove(t *testing.T, p *Parameters) (A, C, input *bls12.EP, B *bls12.EP2) {
+	w := []int64{1, 9, 3}
+	if len(p.A) != 3 || len(p.BG2) != 1 || len(p.IC) != 2 || len(p.L) != 1 || len(p.H) != 3 {
+		t.Fatal("unexpected parameters size")
+	}
+
+	// The evaluations of the constraint polynomials over the domain of size
+	// 4, including the input constraints one * 0 = 0 and y * 0 = 0.
+	a := []int64{3, 1, 9, 0}
+	b := []int64{3, 0, 0, 0}
+	c := []int64{9, 0, 0, 0}
+	pa, pb, pc := interpolate(a), interpolate(b), interpolate(c)
+
+	// h = (a * b - c) / (X^4 - 1)
+	ab := make([]*big.Int, 7)
+	for i := range ab {
+		ab[i] = new(big.Int)
+	}
+	for i := range pa {
+		for j := range pb {
+			ab[i+j].Add(ab[i+j], new(big.Int).Mul(pa[i], pb[j]))
+		}
+	}
+	for i := range pc {
+		ab[i].Sub(ab[i], pc[i])
+	}
+	h := make([]*big.Int, 3)
+	for i := len(ab) - 1; i >= 4; i-- {
+		h[i-4] = new(big.Int).Mod(ab[i], order)
+		ab[i-4].Add(ab[i-4], h[i-4])
+	}
+	for i := 0; i < 4; i++ {
+		if ab[i].Mod(ab[i], order).Sign() != 0 {
+			t.Fatal("the witness does not satisfy the constraints")
+		}
+	}
+
+	A = p.AlphaG1.Copy()
+	B = p.BetaG2.Copy()
+	C = (&bls12.EP{}).SetZero()
+	input = (&bls12.EP{}).SetZero()
+	for i, x := range w {
+		A.Add(p.A[i].Copy().ScalarMult(big.NewInt(x).Bytes()))
+	}
+	B.Add(p.BG2[0].Copy().ScalarMult(big.NewInt(w[2]).Bytes()))
+	for i := range p.IC {
+		input.Add(p.IC[i].Copy().ScalarMult(big.NewInt(w[i]).Bytes()))
+	}
+	C.Add(p.L[0].Copy().ScalarMult(big.NewInt(w[2]).Bytes()))
+	for i := range h {
+		C.Add(p.H[i].Copy().ScalarMult(h[i].Bytes()))
+	}
+	return A, C, input, B
+}
+
+// interpolate returns the coefficients of the polynomial that evaluates to
+// v over the roots of unity of order len(v).
+func interpolate(v []int64) []*big.Int {
+	n := int64(len(v))
+	w := new(big.Int).Exp(big.NewInt(7), new(big.Int).Div(new(big.Int).Sub(order, big.NewInt(1)), big.NewInt(n)), order)
+	wInv := new(big.Int).ModInverse(w, order)
+	nInv := new(big.Int).ModInverse(big.NewInt(n), order)
+	res := make([]*big.Int, n)
+	for i := range res {
+		res[i] = new(big.Int)
+		k := new(big.Int).Exp(wInv, big.NewInt(int64(i)), order)
+		x := big.NewInt(1)
+		for _, y := range v {
+			res[i].Add(res[i], new(big.Int).Mul(x, big.NewInt(y)))
+			x.Mul(x, k).Mod(x, order)
+		}
+		res[i].Mul(res[i], nInv).Mod(res[i], order)
+	}
+	return res
+}
+
+func checkProof(t *testing.T, p *Parameters) {
+	A, C, input, B := prove(t, p)
+	g1 := []*bls12.EP{A.Neg(), p.AlphaG1, input, C}
+	g2 := []*bls12.EP2{B, p.BetaG2, p.GammaG2, p.DeltaG2}
+	if !bls12.PairingCheck(g1, g2) {
+		t.Error("the proof does not verify")
+	}
+}
+
+func TestNewMPCParameters(t *testing.T) {
+	p, err := NewMPCParameters(testAccumulator(), testCircuit(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkProof(t, p.Params)
+
+	var buf bytes.Buffer
+	if err := p.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	p1, err := ReadMPCParameters(&buf, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(p1.CSHash, p.CSHash) || checkFixed(p.Params, p1.Params) != nil {
+		t.Error("parameters changed in the round trip")
+	}
+
+	cs := testCircuit()
+	cs.NumPrivate++
+	if _, err := NewMPCParameters(testAccumulator(), cs, 2); err == nil {
+		t.Error("accepted an unconstrained variable")
+	}
+	cs = testCircuit()
+	cs.Constraints[0].A[0].Variable = 3
+	if _, err := NewMPCParameters(testAccumulator(), cs, 2); err == nil {
+		t.Error("accepted an unknown variable")
+	}
+	cs = testCircuit()
+	for i := 0; i < 8; i++ {
+		cs.Constraints = append(cs.Constraints, cs.Constraints[0])
+	}
+	if _, err := NewMPCParameters(testAccumulator(), cs, 2); err == nil {
+		t.Error("accepted a circuit larger than the accumulator")
+	}
+}
+
+func TestContribute(t *testing.T) {
+	initial, err := NewMPCParameters(testAccumulator(), testCircuit(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := initial.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	p, err := ReadMPCParameters(bytes.NewReader(buf.Bytes()), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hashes := [][]byte{p.Contribute(2), p.Contribute(2)}
+	if p.Params.DeltaG1.Equal(initial.Params.DeltaG1) {
+		t.Error("δ did not change")
+	}
+	checkProof(t, p.Params)
+
+	buf.Reset()
+	if err := p.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	p, err = ReadMPCParameters(&buf, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := p.Verify(initial, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || !bytes.Equal(got[0], hashes[0]) || !bytes.Equal(got[1], hashes[1]) {
+		t.Error("Verify returned the wrong contribution hashes")
+	}
+
+	p.Params.H[0].Double()
+	if _, err := p.Verify(initial, 2); err == nil {
+		t.Error("accepted a modified H")
+	}
+	p.Params.H[0] = p.Params.H[0].ScalarMult((&big.Int{}).ModInverse(big.NewInt(2), order).Bytes())
+	p.Contributions[1].DeltaAfter.Double()
+	if _, err := p.Verify(initial, 2); err == nil {
+		t.Error("accepted a modified public key")
+	}
+	p.Contributions = p.Contributions[:1]
+	if _, err := p.Verify(initial, 2); err == nil {
+		t.Error("accepted a missing contribution")
+	}
+}
+
+func TestReadParametersNotInSubgroup(t *testing.T) {
+	// Points on the curve but not in the subgroup, like in the bls12 tests.
+	var g1 *bls12.EP
+	var g2 *bls12.EP2
+	for x := 1; x < 20; x++ {
+		buf := make([]byte, bls12.G1CompressedSize)
+		buf[0], buf[len(buf)-1] = 1<<7, byte(x)
+		if p, err := (&bls12.EP{}).DecodeCompressed(buf); err == nil && g1 == nil && !p.IsInSubgroup() {
+			g1 = p
+		}
+		buf = make([]byte, bls12.G2CompressedSize)
+		buf[0], buf[len(buf)-1] = 1<<7, byte(x)
+		p := bls12.NewEP2()
+		if _, err := p.DecodeCompressed(buf); err == nil && g2 == nil && !p.IsInSubgroup() {
+			g2 = p
+		} else {
+			p.Close()
+		}
+	}
+	if g1 == nil || g2 == nil {
+		t.Fatal("no valid x found")
+	}
+
+	for _, tc := range []struct {
+		name   string
+		tamper func(p *Parameters)
+	}{
+		{"DeltaG1", func(p *Parameters) { p.DeltaG1 = g1 }},
+		{"DeltaG2", func(p *Parameters) { p.DeltaG2 = g2 }},
+		{"H", func(p *Parameters) { p.H[1] = g1 }},
+		{"L", func(p *Parameters) { p.L[0] = g1 }},
+	} {
+		p, err := NewMPCParameters(testAccumulator(), testCircuit(), 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tc.tamper(p.Params)
+		var buf bytes.Buffer
+		if err := p.WriteTo(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ReadMPCParameters(&buf, false); err == nil {
+			t.Errorf("%s: accepted a point not in the subgroup", tc.name)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package phase2
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/FiloSottile/powersoftau/bls12"
+)
+
+// An R1CS is a rank-1 constraint system over the BLS12-381 scalar field.
+//
+// The variables are numbered with the constant one first, then the
+// NumPublic public inputs, and then the NumPrivate private variables. A
+// witness w satisfies the system if for every constraint
+// <A, w> * <B, w> = <C, w>.
+type R1CS struct {
+	NumPublic   int
+	NumPrivate  int
+	Constraints []Constraint
+}
+
+// A Constraint requires <A, w> * <B, w> = <C, w>.
+type Constraint struct {
+	A, B, C LinearCombination
+}
+
+// A LinearCombination is a sparse sum of variables multiplied by
+// coefficients.
+type LinearCombination []Term
+
+// A Term is a variable multiplied by a coefficient in [0, r).
+type Term struct {
+	Variable int
+	Coeff    *big.Int
+}
+
+// NumVariables returns the number of variables, including the constant one.
+func (cs *R1CS) NumVariables() int {
+	return 1 + cs.NumPublic + cs.NumPrivate
+}
+
+// check returns an error if cs refers to variables that don't exist, or
+// has coefficients that are not reduced.
+func (cs *R1CS) check() error {
+	if cs.NumPublic < 0 || cs.NumPrivate < 0 {
+		return errors.New("invalid number of variables")
+	}
+	r := (&big.Int{}).SetBytes(bls12.ScalarOrder())
+	for i, c := range cs.Constraints {
+		for _, lc := range []LinearCombination{c.A, c.B, c.C} {
+			for _, t := range lc {
+				if t.Variable < 0 || t.Variable >= cs.NumVariables() {
+					return fmt.Errorf("constraint %d refers to unknown variable %d", i, t.Variable)
+				}
+				if t.Coeff == nil || t.Coeff.Sign() < 0 || t.Coeff.Cmp(r) >= 0 {
+					return fmt.Errorf("constraint %d has an invalid coefficient", i)
+				}
+			}
+		}
+	}
+	return nil
+}
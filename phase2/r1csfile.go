@@ -0,0 +1,256 @@
+package phase2
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/FiloSottile/powersoftau/bls12"
+)
+
+/*
+
+The iden3 .r1cs format, written by circom, is a sequence of sections after
+a "r1cs" magic, a 32-bit version (1) and the 32-bit number of sections. Each
+section starts with a 32-bit type and a 64-bit size. All integers are
+little-endian, including the field elements, which are not in Montgomery
+form. [iden3/r1csfile]
+
+	1. Header: the size in bytes n8 of a field element, the prime, the
+	   number of wires, public outputs, public inputs and private inputs,
+	   the 64-bit number of labels, and the number of constraints
+
+	2. Constraints: for each constraint, the A, B and C linear combinations
+	   as a 32-bit number of terms followed by the terms, each a 32-bit wire
+	   and an n8-byte coefficient
+
+	3. Wire to label map: the 64-bit label of each wire
+
+Wire 0 is the constant one, followed by the public outputs, the public
+inputs, and then the private inputs and the internal wires, so wires map
+directly to the variables of an R1CS.
+
+*/
+
+const (
+	r1csHeader      = 1
+	r1csConstraints = 2
+	r1csWireToLabel = 3
+)
+
+// ReadR1CS reads a circuit in the iden3 binary .r1cs format. It returns
+// the constraint system and the wire-to-label map, where labels[i] is the
+// label of variable i.
+//
+// The sections can come in any order: circom 2 writes the constraints
+// before the header they depend on, so r is read twice, first to find the
+// sections and then to parse them. Unknown sections are skipped. The circuit
+// must be over the BLS12-381 scalar field, like circom produces with
+// --prime bls12381.
+func ReadR1CS(r io.ReadSeeker) (cs *R1CS, labels []uint64, err error) {
+	var magic [4]byte
+	if _, err := io.ReadFull(r, magic[:]); err != nil {
+		return nil, nil, err
+	}
+	if string(magic[:]) != "r1cs" {
+		return nil, nil, errors.New("not a .r1cs file")
+	}
+	var buf [8]byte
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		return nil, nil, err
+	}
+	if v := binary.LittleEndian.Uint32(buf[:4]); v != 1 {
+		return nil, nil, fmt.Errorf("unsupported .r1cs version %d", v)
+	}
+	nSections := binary.LittleEndian.Uint32(buf[4:])
+
+	type section struct{ offset, size int64 }
+	sections := make(map[uint32]section)
+	offset := int64(len(magic) + len(buf))
+	for i := uint32(0); i < nSections; i++ {
+		var buf [12]byte
+		if _, err := io.ReadFull(r, buf[:]); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, nil, err
+		}
+		typ := binary.LittleEndian.Uint32(buf[:4])
+		size := binary.LittleEndian.Uint64(buf[4:])
+		if size > 1<<62 {
+			return nil, nil, fmt.Errorf("invalid size of section %d", typ)
+		}
+		offset += int64(len(buf))
+		if typ == r1csHeader || typ == r1csConstraints || typ == r1csWireToLabel {
+			if _, ok := sections[typ]; ok {
+				return nil, nil, fmt.Errorf("duplicate section %d", typ)
+			}
+			sections[typ] = section{offset, int64(size)}
+		}
+		offset += int64(size)
+		if _, err := r.Seek(offset, io.SeekStart); err != nil {
+			return nil, nil, err
+		}
+	}
+	if len(sections) != 3 {
+		return nil, nil, errors.New("missing sections")
+	}
+
+	// readSection calls f on the contents of the section, which it must
+	// consume entirely.
+	readSection := func(typ uint32, f func(r io.Reader) error) error {
+		s := sections[typ]
+		if _, err := r.Seek(s.offset, io.SeekStart); err != nil {
+			return err
+		}
+		sr := bufio.NewReader(io.LimitReader(r, s.size))
+		err := f(sr)
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := sr.ReadByte(); err != io.EOF {
+			return fmt.Errorf("section %d is longer than its contents", typ)
+		}
+		return nil
+	}
+
+	var h *r1csFileHeader
+	if err := readSection(r1csHeader, func(r io.Reader) (err error) {
+		h, err = readR1CSHeader(r)
+		return err
+	}); err != nil {
+		return nil, nil, err
+	}
+	cs = &R1CS{NumPublic: h.nPublic, NumPrivate: h.nWires - 1 - h.nPublic}
+	if err := readSection(r1csConstraints, func(r io.Reader) (err error) {
+		cs.Constraints, err = readR1CSConstraints(r, h)
+		return err
+	}); err != nil {
+		return nil, nil, err
+	}
+	if err := readSection(r1csWireToLabel, func(r io.Reader) (err error) {
+		labels, err = readR1CSLabels(r, h)
+		return err
+	}); err != nil {
+		return nil, nil, err
+	}
+
+	if len(cs.Constraints) != h.nConstraints {
+		return nil, nil, errors.New("wrong number of constraints")
+	}
+	if err := cs.check(); err != nil {
+		return nil, nil, err
+	}
+	return cs, labels, nil
+}
+
+type r1csFileHeader struct {
+	n8           int
+	nWires       int
+	nPublic      int
+	nConstraints int
+}
+
+func readR1CSHeader(r io.Reader) (*r1csFileHeader, error) {
+	var buf [4]byte
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		return nil, err
+	}
+	h := &r1csFileHeader{n8: int(binary.LittleEndian.Uint32(buf[:]))}
+	if h.n8 != 32 {
+		return nil, errors.New("the circuit is not over the BLS12-381 scalar field")
+	}
+	prime := make([]byte, h.n8)
+	if _, err := io.ReadFull(r, prime); err != nil {
+		return nil, err
+	}
+	order := (&big.Int{}).SetBytes(bls12.ScalarOrder())
+	if (&big.Int{}).SetBytes(reverse(prime)).Cmp(order) != 0 {
+		return nil, errors.New("the circuit is not over the BLS12-381 scalar field")
+	}
+
+	var counts [4*4 + 8 + 4]byte
+	if _, err := io.ReadFull(r, counts[:]); err != nil {
+		return nil, err
+	}
+	nWires := binary.LittleEndian.Uint32(counts[0:])
+	nPubOut := binary.LittleEndian.Uint32(counts[4:])
+	nPubIn := binary.LittleEndian.Uint32(counts[8:])
+	nConstraints := binary.LittleEndian.Uint32(counts[24:])
+	if nWires == 0 || uint64(nPubOut)+uint64(nPubIn) >= uint64(nWires) {
+		return nil, errors.New("invalid number of wires")
+	}
+	if uint64(nWires) > 1<<31-1 || uint64(nConstraints) > 1<<31-1 {
+		return nil, errors.New("the circuit is too large")
+	}
+	h.nWires = int(nWires)
+	h.nPublic = int(nPubOut + nPubIn)
+	h.nConstraints = int(nConstraints)
+	return h, nil
+}
+
+func readR1CSConstraints(r io.Reader, h *r1csFileHeader) ([]Constraint, error) {
+	var res []Constraint
+	for i := 0; i < h.nConstraints; i++ {
+		var c Constraint
+		for _, lc := range []*LinearCombination{&c.A, &c.B, &c.C} {
+			var err error
+			if *lc, err = readR1CSLinearCombination(r, h); err != nil {
+				return nil, err
+			}
+		}
+		res = append(res, c)
+	}
+	return res, nil
+}
+
+func readR1CSLinearCombination(r io.Reader, h *r1csFileHeader) (LinearCombination, error) {
+	var buf [4]byte
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		return nil, err
+	}
+	n := binary.LittleEndian.Uint32(buf[:])
+	var lc LinearCombination
+	coeff := make([]byte, h.n8)
+	for i := uint32(0); i < n; i++ {
+		if _, err := io.ReadFull(r, buf[:]); err != nil {
+			return nil, err
+		}
+		if _, err := io.ReadFull(r, coeff); err != nil {
+			return nil, err
+		}
+		lc = append(lc, Term{
+			Variable: int(binary.LittleEndian.Uint32(buf[:])),
+			Coeff:    (&big.Int{}).SetBytes(reverse(coeff)),
+		})
+	}
+	return lc, nil
+}
+
+func readR1CSLabels(r io.Reader, h *r1csFileHeader) ([]uint64, error) {
+	var buf [8]byte
+	var res []uint64
+	for i := 0; i < h.nWires; i++ {
+		if _, err := io.ReadFull(r, buf[:]); err != nil {
+			return nil, err
+		}
+		res = append(res, binary.LittleEndian.Uint64(buf[:]))
+	}
+	return res, nil
+}
+
+// reverse returns a reversed copy of b, to convert between little-endian
+// and big-endian.
+func reverse(b []byte) []byte {
+	res := make([]byte, len(b))
+	for i := range b {
+		res[len(b)-1-i] = b[i]
+	}
+	return res
+}
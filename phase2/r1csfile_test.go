@@ -0,0 +1,254 @@
+package phase2
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/FiloSottile/powersoftau/bls12"
+)
+
+// r1csBuilder writes a .r1cs file by hand, to test ReadR1CS independently.
+type r1csBuilder struct {
+	sections [][]byte
+}
+
+func (b *r1csBuilder) section(typ uint32, data []byte) {
+	s := make([]byte, 12, 12+len(data))
+	binary.LittleEndian.PutUint32(s, typ)
+	binary.LittleEndian.PutUint64(s[4:], uint64(len(data)))
+	b.sections = append(b.sections, append(s, data...))
+}
+
+func (b *r1csBuilder) header(prime []byte, nWires, nPubOut, nPubIn, nConstraints uint32) {
+	var h []byte
+	h = appendUint32(h, uint32(len(prime)))
+	h = append(h, reverse(prime)...)
+	h = appendUint32(h, nWires)
+	h = appendUint32(h, nPubOut)
+	h = appendUint32(h, nPubIn)
+	h = appendUint32(h, nWires-1-nPubOut-nPubIn)
+	h = append(h, make([]byte, 8)...)
+	binary.LittleEndian.PutUint64(h[len(h)-8:], uint64(nWires))
+	h = appendUint32(h, nConstraints)
+	b.section(r1csHeader, h)
+}
+
+func (b *r1csBuilder) constraints(cs []Constraint) {
+	var s []byte
+	for _, c := range cs {
+		for _, lc := range []LinearCombination{c.A, c.B, c.C} {
+			s = appendUint32(s, uint32(len(lc)))
+			for _, t := range lc {
+				s = appendUint32(s, uint32(t.Variable))
+				coeff := make([]byte, 32)
+				copy(coeff[32-len(t.Coeff.Bytes()):], t.Coeff.Bytes())
+				s = append(s, reverse(coeff)...)
+			}
+		}
+	}
+	b.section(r1csConstraints, s)
+}
+
+func (b *r1csBuilder) labels(labels []uint64) {
+	var s []byte
+	for _, l := range labels {
+		var buf [8]byte
+		binary.LittleEndian.PutUint64(buf[:], l)
+		s = append(s, buf[:]...)
+	}
+	b.section(r1csWireToLabel, s)
+}
+
+func (b *r1csBuilder) bytes() []byte {
+	res := []byte("r1cs")
+	res = appendUint32(res, 1)
+	res = appendUint32(res, uint32(len(b.sections)))
+	for _, s := range b.sections {
+		res = append(res, s...)
+	}
+	return res
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+// testR1CSFile returns a builder for testCircuit, with the sections in the
+// order circom 2 writes them: the constraints, the header, and the labels.
+func testR1CSFile() *r1csBuilder {
+	b := &r1csBuilder{}
+	b.constraints(testCircuit().Constraints)
+	b.header(order.Bytes(), 3, 1, 0, 1)
+	b.labels([]uint64{0, 1, 2})
+	return b
+}
+
+func TestReadR1CS(t *testing.T) {
+	cs, labels, err := ReadR1CS(bytes.NewReader(testR1CSFile().bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkR1CS(t, cs, testCircuit())
+	if len(labels) != 3 || labels[0] != 0 || labels[1] != 1 || labels[2] != 2 {
+		t.Errorf("wrong labels %v", labels)
+	}
+	if _, err := NewMPCParameters(testAccumulator(), cs, 2); err != nil {
+		t.Errorf("NewMPCParameters failed: %v", err)
+	}
+
+	// out = 3 * a * (b - 1), with a private intermediate wire, an input
+	// constraint on a public input, and the header last with an unknown
+	// section in the middle.
+	minusOne := (&big.Int{}).Sub(order, big.NewInt(1))
+	want := &R1CS{
+		NumPublic:  2,
+		NumPrivate: 2,
+		Constraints: []Constraint{{
+			A: LinearCombination{{3, big.NewInt(3)}},
+			B: LinearCombination{{2, big.NewInt(1)}, {0, minusOne}},
+			C: LinearCombination{{4, big.NewInt(1)}},
+		}, {
+			A: LinearCombination{{4, big.NewInt(1)}},
+			B: LinearCombination{{0, big.NewInt(1)}},
+			C: LinearCombination{{1, big.NewInt(1)}},
+		}, {
+			A: LinearCombination{},
+			B: LinearCombination{},
+			C: LinearCombination{},
+		}},
+	}
+	b := &r1csBuilder{}
+	b.labels([]uint64{0, 1, 2, 3, 7})
+	b.section(42, []byte("custom gates"))
+	b.constraints(want.Constraints)
+	b.header(order.Bytes(), 5, 1, 1, 3)
+	cs, labels, err = ReadR1CS(bytes.NewReader(b.bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkR1CS(t, cs, want)
+	if len(labels) != 5 || labels[4] != 7 {
+		t.Errorf("wrong labels %v", labels)
+	}
+}
+
+func checkR1CS(t *testing.T, got, want *R1CS) {
+	t.Helper()
+	if got.NumPublic != want.NumPublic || got.NumPrivate != want.NumPrivate {
+		t.Errorf("got %d public and %d private variables, want %d and %d",
+			got.NumPublic, got.NumPrivate, want.NumPublic, want.NumPrivate)
+	}
+	if len(got.Constraints) != len(want.Constraints) {
+		t.Fatalf("got %d constraints, want %d", len(got.Constraints), len(want.Constraints))
+	}
+	for i := range want.Constraints {
+		g, w := got.Constraints[i], want.Constraints[i]
+		for j, lcs := range [][2]LinearCombination{{g.A, w.A}, {g.B, w.B}, {g.C, w.C}} {
+			if len(lcs[0]) != len(lcs[1]) {
+				t.Errorf("constraint %d, LC %d: got %d terms, want %d", i, j, len(lcs[0]), len(lcs[1]))
+				continue
+			}
+			for k := range lcs[1] {
+				if lcs[0][k].Variable != lcs[1][k].Variable || lcs[0][k].Coeff.Cmp(lcs[1][k].Coeff) != 0 {
+					t.Errorf("constraint %d, LC %d: wrong term %d", i, j, k)
+				}
+			}
+		}
+	}
+}
+
+func TestReadR1CSErrors(t *testing.T) {
+	// The BN254 scalar field, the circom default.
+	bn254, _ := (&big.Int{}).SetString("21888242871839275222246405745257275088548364400416034343698204186575808495617", 10)
+	tooLarge := (&big.Int{}).Add(order, big.NewInt(1))
+
+	tests := []struct {
+		name  string
+		build func() []byte
+		err   string
+	}{
+		{"magic", func() []byte {
+			b := testR1CSFile().bytes()
+			copy(b, "r2cs")
+			return b
+		}, "not a .r1cs file"},
+		{"version", func() []byte {
+			b := testR1CSFile().bytes()
+			b[4] = 2
+			return b
+		}, "version"},
+		{"prime", func() []byte {
+			b := &r1csBuilder{}
+			b.header(bn254.Bytes(), 3, 1, 0, 1)
+			b.constraints(testCircuit().Constraints)
+			b.labels([]uint64{0, 1, 2})
+			return b.bytes()
+		}, "BLS12-381"},
+		{"field size", func() []byte {
+			b := &r1csBuilder{}
+			b.header(bls12.ScalarOrder(), 3, 1, 0, 1)
+			b.constraints(testCircuit().Constraints)
+			b.labels([]uint64{0, 1, 2})
+			return b.bytes()
+		}, "BLS12-381"},
+		{"missing", func() []byte {
+			b := testR1CSFile()
+			b.sections = b.sections[:2]
+			return b.bytes()
+		}, "missing"},
+		{"duplicate", func() []byte {
+			b := testR1CSFile()
+			b.sections = append(b.sections, b.sections[2])
+			return b.bytes()
+		}, "duplicate"},
+		{"truncated", func() []byte {
+			b := testR1CSFile().bytes()
+			return b[:len(b)-1]
+		}, "EOF"},
+		{"section size", func() []byte {
+			b := testR1CSFile()
+			s := b.sections[2]
+			binary.LittleEndian.PutUint64(s[4:], uint64(len(s)-12+8))
+			b.sections[2] = append(s, make([]byte, 8)...)
+			return b.bytes()
+		}, "longer"},
+		{"constraints", func() []byte {
+			b := &r1csBuilder{}
+			b.header(order.Bytes(), 3, 1, 0, 2)
+			b.constraints(testCircuit().Constraints)
+			b.labels([]uint64{0, 1, 2})
+			return b.bytes()
+		}, "EOF"},
+		{"wire", func() []byte {
+			cs := testCircuit()
+			cs.Constraints[0].C[0].Variable = 3
+			b := &r1csBuilder{}
+			b.header(order.Bytes(), 3, 1, 0, 1)
+			b.constraints(cs.Constraints)
+			b.labels([]uint64{0, 1, 2})
+			return b.bytes()
+		}, "unknown variable"},
+		{"coefficient", func() []byte {
+			cs := testCircuit()
+			cs.Constraints[0].C[0].Coeff = tooLarge
+			b := &r1csBuilder{}
+			b.header(order.Bytes(), 3, 1, 0, 1)
+			b.constraints(cs.Constraints)
+			b.labels([]uint64{0, 1, 2})
+			return b.bytes()
+		}, "invalid coefficient"},
+	}
+	for _, tt := range tests {
+		_, _, err := ReadR1CS(bytes.NewReader(tt.build()))
+		if err == nil {
+			t.Errorf("%s: accepted an invalid file", tt.name)
+		} else if !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: got error %q, want %q", tt.name, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package powersoftau
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math/big"
+
+	"github.com/FiloSottile/powersoftau/bls12"
+	"github.com/FiloSottile/powersoftau/internal/chacha20"
+)
+
+/*
+
+A random beacon contribution is a contribution where all the randomness is
+derived from a public value, like a future block hash, so that anyone can
+reproduce it. To match the Rust beacon binary byte for byte:
+
+	1. Hash the beacon value with SHA-256 2^n times [beacon]
+
+	2. Seed a ChaCha20 RNG with the result, like in HashToG2
+
+	3. Generate the keypair like in NewKeypair, but with randomness from
+	   the RNG [keypair]
+		3.1. Pick tau, alpha and beta in that order [Fr::rand]
+			3.1.1. Extract 8 uint32 from the RNG and arrange them into
+			       a 256-bit number like in HashToG2 step 2.1.1
+			       [FrRepr::rand]
+			3.1.2. Mask away the top bit [FrRepr::rand]
+			3.1.3. If the result is not lower than the group order,
+			       go back to 3.1.1 [Fr::is_valid]
+			3.1.4. Perform a Montgomery reduction: the Rust code uses
+			       the number as the Montgomery form of the scalar
+			       [Fr::into_repr]
+		3.2. For each of tau, alpha and beta, pick S [G1::rand]
+			3.2.1. Pick a random x like in HashToG2 step 2.1
+			3.2.2. Pick a random flag like in HashToG2 step 3
+			3.2.3. Decompress the point x with the flag, and scale it
+			       by the G1 cofactor, like in HashToG2 steps 4-6
+
+*/
+
+// MaxBeaconIterations is the largest supported base 2 logarithm of the
+// number of beacon hash iterations.
+const MaxBeaconIterations = 63
+
+// IterateBeacon hashes beacon with SHA-256 2^n times, and returns the
+// seed for BeaconKeypair.
+func IterateBeacon(beacon []byte, n uint) ([]byte, error) {
+	if n > MaxBeaconIterations {
+		return nil, errors.New("too many beacon iterations")
+	}
+	h := append([]byte{}, beacon...)
+	for i := uint64(0); i < 1<<n; i++ {
+		sum := sha256.Sum256(h)
+		h = sum[:]
+	}
+	return h, nil
+}
+
+// BeaconKeypair returns a NewKeypair replacement that deterministically
+// derives the keypair from seed, as returned by IterateBeacon.
+func BeaconKeypair(seed []byte) func(digest []byte) (*PublicKey, *PrivateKey) {
+	seed = append([]byte{}, seed...)
+	return func(digest []byte) (*PublicKey, *PrivateKey) {
+		rng := newRustRng(seed)
+		return newKeypair(digest, func() *bls12.Fr {
+			return extractScalar(rng)
+		}, func() *bls12.EP {
+			return extractG1(rng)
+		})
+	}
+}
+
+// frRInv is the inverse of the Montgomery R = 2^256 modulo the group order.
+var frRInv = func() *bls12.Fr {
+	r := (&big.Int{}).SetBytes(bls12.ScalarOrder())
+	R := (&big.Int{}).Lsh(big.NewInt(1), 256)
+	fr, err := (&bls12.Fr{}).SetBytes(R.ModInverse(R.Mod(R, r), r).Bytes())
+	if err != nil {
+		panic(err)
+	}
+	return fr
+}()
+
+// extractScalar samples a scalar like the Rust Fr::rand, which takes the
+// random limbs as the Montgomery representation, so the value is divided by
+// R.
+func extractScalar(rng *chacha20.Rng) *bls12.Fr {
+	for {
+		var res [32]byte
+		for i := 32 - 8; i >= 0; i -= 8 {
+			binary.BigEndian.PutUint32(res[i:], rng.ReadUint32())
+			binary.BigEndian.PutUint32(res[i+4:], rng.ReadUint32())
+		}
+		res[0] &= 0xff >> 1
+		k, err := (&bls12.Fr{}).SetBytes(res[:])
+		ZeroBytes(res[:])
+		if err != nil {
+			continue
+		}
+		return k.Mul(frRInv)
+	}
+}
+
+func extractG1(rng *chacha20.Rng) *bls12.EP {
+	for {
+		x := extractFieldElement(rng)
+		greater := extractBool(rng)
+
+		buf := make([]byte, bls12.G1CompressedSize)
+		copy(buf, x[:])
+		buf[0] |= 1 << 7 // serializationCompressed
+		if greater {
+			buf[0] |= 1 << 5 // serializationBigY
+		}
+
+		p, err := (&bls12.EP{}).DecodeCompressed(buf)
+		if err != nil {
+			continue
+		}
+
+		p.ScaleByCofactor()
+
+		if p.IsZero() {
+			continue
+		}
+
+		return p
+	}
+}
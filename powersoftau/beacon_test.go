@@ -0,0 +1,124 @@
+package powersoftau
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/FiloSottile/powersoftau/bls12"
+)
+
+func TestIterateBeacon(t *testing.T) {
+	beacon, _ := hex.DecodeString("0000000000000000000a558a61ddc8ee4e488d647a747fe4dcc362fe2026c620")
+	expected := "bb8eef164289bf02058b42c4ca2ac72013deb099bc79067e5779c9f1728b1271"
+	seed, err := IterateBeacon(beacon, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(seed) != expected {
+		t.Errorf("got %x, expected %s", seed, expected)
+	}
+	if _, err := IterateBeacon(beacon, MaxBeaconIterations+1); err == nil {
+		t.Error("accepted too many iterations")
+	}
+}
+
+func TestBeaconKeypair(t *testing.T) {
+	// Computed from the seed of TestIterateBeacon by a Python model of the
+	// Rust beacon: rand 0.4 ChaChaRng, Fr::rand and G1::rand.
+	seed, _ := hex.DecodeString("bb8eef164289bf02058b42c4ca2ac72013deb099bc79067e5779c9f1728b1271")
+	expected := []struct{ x, s, sx string }{{
+		"67cdc0218d85aaa2e7a5ca16b09c3a7bac596b369bff293a071173997b250a61",
+		"b1c56c3fefed80f5d7c9ad06e96b3b691e4db0d8027bb33bcdd90e9c14d4076da770fc35028ae59f6b627256f8578fab",
+		"b8f054f4b198b117dd4374a78781184cabc8f80c81b57d7c37b82dc8cf124d735add2fbdd8ff7581ff812f5d71bfcc4b",
+	}, {
+		"03ea86d2f12ffae4ae6733b275cad202e1f6df463ef330c41f91b160b1deeea5",
+		"902db87e3ed3fccb279307b5ac2b922e78ced06beb4a83a2d01ae64692471cd0700f56e9f544332e2b5914fcbce21534",
+		"8eefff8a5ed2d06e241875ce3cb578423f176f3e629cc650a05f97542df3d7a98a7bd2550741b527de9bfdb13641878c",
+	}, {
+		"5e3e3be3c05e0875209c2fb3b1b5dafdd421c882d63f8cbce787d09b98c752e0",
+		"88a05fa84765187bd884f5bfc35a2e222ad27d983c4bef55cf2e1c6d814a60d45eb7113fdc90eec7eea6504c3f7e6c7b",
+		"8419b6f72ff79bff1ca01eafeb41637de12d66ade4c51fdbd067286debed43c8de2f5422d90486fbd3a9f0a162aa7396",
+	}}
+
+	pub, priv := BeaconKeypair(seed)(make([]byte, 64))
+	defer priv.Destroy()
+	for i, k := range []struct {
+		name string
+		x    []byte
+		s    *bls12.EP
+		sx   *bls12.EP
+	}{
+		{"tau", priv.Tau, pub.Tau.S, pub.Tau.Sx},
+		{"alpha", priv.Alpha, pub.Alpha.S, pub.Alpha.Sx},
+		{"beta", priv.Beta, pub.Beta.S, pub.Beta.Sx},
+	} {
+		if got := hex.EncodeToString(k.x); got != expected[i].x {
+			t.Errorf("%s: got %s, expected %s", k.name, got, expected[i].x)
+		}
+		if got := hex.EncodeToString(k.s.EncodeCompressed()); got != expected[i].s {
+			t.Errorf("%s: got S %s, expected %s", k.name, got, expected[i].s)
+		}
+		if got := hex.EncodeToString(k.sx.EncodeCompressed()); got != expected[i].sx {
+			t.Errorf("%s: got Sx %s, expected %s", k.name, got, expected[i].sx)
+		}
+	}
+}
+
+func TestBeaconContribution(t *testing.T) {
+	dir, err := ioutil.TempDir("", "powersoftau")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	challengeFile := filepath.Join(dir, "challenge")
+	writeTestChallenge(t, challengeFile, testParams)
+
+	seed, err := IterateBeacon([]byte("beacon"), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Two beacon contributions from the same seed must be identical, and
+	// valid, whether computed in memory or streamed.
+	c, err := ReadChallenge(challengeFile, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.ComputeWith(2, BeaconKeypair(seed)); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteResponse(filepath.Join(dir, "response1"), c); err != nil {
+		t.Fatal(err)
+	}
+	s, err := ComputeStream(challengeFile, filepath.Join(dir, "response2"), &StreamOptions{
+		Processes: 2, NewKeypair: BeaconKeypair(seed),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(c.ResponseHash, s.ResponseHash) {
+		t.Error("beacon contributions are not reproducible")
+	}
+
+	r, err := ReadResponse(filepath.Join(dir, "response2"), nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch, err := ReadChallenge(challengeFile, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ch.Verify(r, 2); err != nil {
+		t.Errorf("beacon response failed verification: %v", err)
+	}
+
+	other, _ := IterateBeacon([]byte("beacon"), 5)
+	pub, _ := BeaconKeypair(other)(c.ChallengeHash)
+	if pub.Tau.S.Equal(c.PublicKey.Tau.S) {
+		t.Error("different beacons produced the same keypair")
+	}
+}
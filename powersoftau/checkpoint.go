@@ -0,0 +1,255 @@
+package powersoftau
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+
+	"github.com/FiloSottile/powersoftau/bls12"
+	"golang.org/x/crypto/blake2b"
+)
+
+// A checkpoint file records the progress of ComputeStream, so that it can
+// be resumed with the same keypair to produce an identical response.
+//
+// It contains toxic waste, the private key, so that is sealed with
+// AES-256-GCM under a key derived from a passphrase with
+// PBKDF2-HMAC-SHA256 and a random salt. The file is also created readable
+// only by the owner, it's only ever overwritten in place, and it's zeroed
+// before being removed on completion.
+//
+// The format is:
+//
+//	magic          [8]byte
+//	challenge hash [64]byte
+//	public key     [PublicKeySize]byte
+//	iterations     uint32       (of PBKDF2)
+//	salt           [16]byte
+//	private key    [3*32+16]byte (tau, alpha, beta, sealed)
+//	section        uint32       (index of the section in progress)
+//	done           uint64       (points of that section already written)
+//	checksum       [8]byte      (BLAKE2b of all the above, truncated)
+//
+// The private key is sealed with an all-zero nonce, as each key is used
+// only once, and with all the fields before it as additional data. Only
+// the last three fields are updated, with a single small write.
+type checkpoint struct {
+	f      *os.File
+	header []byte
+}
+
+var checkpointMagic = []byte("ptauckp2")
+
+const (
+	checkpointScalarSize = 32
+	checkpointSaltSize   = 16
+	checkpointSealedSize = 3*checkpointScalarSize + 16
+	checkpointHeaderSize = 8 + blake2b.Size + PublicKeySize + 4 + checkpointSaltSize + checkpointSealedSize
+)
+
+// checkpointIterations is the PBKDF2 work factor of new checkpoints.
+var checkpointIterations = 1 << 20
+
+func createCheckpoint(filename string, challengeHash, passphrase []byte, pub *PublicKey, priv *PrivateKey) (*checkpoint, error) {
+	buf := &bytes.Buffer{}
+	if err := pub.WriteTo(buf); err != nil {
+		return nil, err
+	}
+	header := make([]byte, 0, checkpointHeaderSize)
+	header = append(header, checkpointMagic...)
+	header = append(header, challengeHash...)
+	header = append(header, buf.Bytes()...)
+	var iter [4]byte
+	binary.BigEndian.PutUint32(iter[:], uint32(checkpointIterations))
+	header = append(header, iter[:]...)
+	salt := make([]byte, checkpointSaltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+	header = append(header, salt...)
+
+	aead := checkpointAEAD(passphrase, salt, checkpointIterations)
+	var plaintext [3 * checkpointScalarSize]byte
+	defer ZeroBytes(plaintext[:])
+	for i, s := range [][]byte{priv.Tau, priv.Alpha, priv.Beta} {
+		copy(plaintext[(i+1)*checkpointScalarSize-len(s):], s)
+	}
+	aad := append([]byte{}, header...)
+	header = aead.Seal(header, make([]byte, aead.NonceSize()), plaintext[:], aad)
+
+	f, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, err
+	}
+	cp := &checkpoint{f: f, header: header}
+	if _, err := f.Write(header); err != nil {
+		cp.erase()
+		return nil, err
+	}
+	if err := cp.update(0, 0); err != nil {
+		cp.erase()
+		return nil, err
+	}
+	return cp, nil
+}
+
+// openCheckpoint opens an existing checkpoint, checks that it was made for
+// the challenge with the given hash and parameters, and unseals the private
+// key with passphrase.
+func openCheckpoint(filename string, challengeHash, passphrase []byte, params *Parameters) (cp *checkpoint, pub *PublicKey, priv *PrivateKey, section, done int, err error) {
+	f, err := os.OpenFile(filename, os.O_RDWR, 0)
+	if err != nil {
+		return nil, nil, nil, 0, 0, err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+		}
+	}()
+
+	buf := make([]byte, checkpointHeaderSize+4+8+8)
+	if _, err := io.ReadFull(f, buf); err != nil {
+		return nil, nil, nil, 0, 0, errors.New("the checkpoint file is truncated")
+	}
+	header, progress := buf[:checkpointHeaderSize], buf[checkpointHeaderSize:]
+
+	if !bytes.Equal(header[:len(checkpointMagic)], checkpointMagic) {
+		return nil, nil, nil, 0, 0, errors.New("the checkpoint file is not valid")
+	}
+	if !bytes.Equal(progress[12:], checkpointChecksum(header, progress[:12])) {
+		return nil, nil, nil, 0, 0, errors.New("the checkpoint file is corrupted")
+	}
+	h := header[len(checkpointMagic):]
+	if !bytes.Equal(h[:blake2b.Size], challengeHash) {
+		return nil, nil, nil, 0, 0, errors.New("the checkpoint is for a different challenge")
+	}
+	h = h[blake2b.Size:]
+
+	section64 := binary.BigEndian.Uint32(progress)
+	done64 := binary.BigEndian.Uint64(progress[4:])
+	sections := accumulatorSections(params, nil, nil)
+	if uint64(section64) >= uint64(len(sections)) || done64 > uint64(sections[section64].n) {
+		return nil, nil, nil, 0, 0, errors.New("the checkpoint file is not valid")
+	}
+
+	pub, err = ReadPublicKey(bytes.NewReader(h[:PublicKeySize]), false)
+	if err != nil {
+		return nil, nil, nil, 0, 0, err
+	}
+	h = h[PublicKeySize:]
+	iter := binary.BigEndian.Uint32(h)
+	if iter == 0 || iter > 1<<30 {
+		return nil, nil, nil, 0, 0, errors.New("the checkpoint file is not valid")
+	}
+	salt, sealed := h[4:4+checkpointSaltSize], h[4+checkpointSaltSize:]
+
+	aead := checkpointAEAD(passphrase, salt, int(iter))
+	aad := header[:len(header)-len(sealed)]
+	plaintext, err := aead.Open(nil, make([]byte, aead.NonceSize()), sealed, aad)
+	if err != nil {
+		return nil, nil, nil, 0, 0, errors.New("wrong passphrase for the checkpoint file")
+	}
+	defer ZeroBytes(plaintext)
+	var scalars [3][]byte
+	for i := range scalars {
+		scalars[i] = append([]byte{}, plaintext[i*checkpointScalarSize:(i+1)*checkpointScalarSize]...)
+	}
+	priv = newPrivateKey(scalars[0], scalars[1], scalars[2])
+	if !bls12.IsScalar(priv.Tau) || !bls12.IsScalar(priv.Alpha) || !bls12.IsScalar(priv.Beta) {
+		priv.Destroy()
+		return nil, nil, nil, 0, 0, errors.New("the checkpoint file is not valid")
+	}
+
+	return &checkpoint{f: f, header: header}, pub, priv, int(section64), int(done64), nil
+}
+
+// checkpointAEAD returns the AES-256-GCM instance keyed with the PBKDF2 of
+// passphrase.
+func checkpointAEAD(passphrase, salt []byte, iter int) cipher.AEAD {
+	key := pbkdf2(passphrase, salt, iter)
+	defer ZeroBytes(key)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		panic(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		panic(err)
+	}
+	return aead
+}
+
+// pbkdf2 returns the first 32 bytes of PBKDF2-HMAC-SHA256 (RFC 8018), which
+// are a single block.
+func pbkdf2(passphrase, salt []byte, iter int) []byte {
+	prf := hmac.New(sha256.New, passphrase)
+	prf.Write(salt)
+	prf.Write([]byte{0, 0, 0, 1})
+	u := prf.Sum(nil)
+	t := append([]byte{}, u...)
+	for i := 1; i < iter; i++ {
+		prf.Reset()
+		prf.Write(u)
+		u = prf.Sum(u[:0])
+		for j := range t {
+			t[j] ^= u[j]
+		}
+	}
+	ZeroBytes(u)
+	return t
+}
+
+func checkpointChecksum(header, progress []byte) []byte {
+	h, _ := blake2b.New512(nil)
+	h.Write(header)
+	h.Write(progress)
+	return h.Sum(nil)[:8]
+}
+
+// update records that the first done points of section have been durably
+// written to the output files.
+func (cp *checkpoint) update(section, done int) error {
+	progress := make([]byte, 12, 20)
+	binary.BigEndian.PutUint32(progress, uint32(section))
+	binary.BigEndian.PutUint64(progress[4:], uint64(done))
+	progress = append(progress, checkpointChecksum(cp.header, progress)...)
+	if _, err := cp.f.WriteAt(progress, int64(len(cp.header))); err != nil {
+		return err
+	}
+	return cp.f.Sync()
+}
+
+// Close closes the file, leaving the checkpoint in place. It does nothing if
+// the checkpoint was already closed or erased.
+func (cp *checkpoint) Close() error {
+	if cp.f == nil {
+		return nil
+	}
+	err := cp.f.Close()
+	cp.f = nil
+	return err
+}
+
+// erase overwrites the checkpoint with zeroes, and then removes and closes
+// it.
+func (cp *checkpoint) erase() error {
+	defer cp.Close()
+	fi, err := cp.f.Stat()
+	if err != nil {
+		return err
+	}
+	if _, err := cp.f.WriteAt(make([]byte, fi.Size()), 0); err != nil {
+		return err
+	}
+	if err := cp.f.Sync(); err != nil {
+		return err
+	}
+	return os.Remove(cp.f.Name())
+}
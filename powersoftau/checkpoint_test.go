@@ -0,0 +1,114 @@
+package powersoftau
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+func TestPBKDF2(t *testing.T) {
+	// From RFC 7914, Section 11.
+	for _, tt := range []struct {
+		passphrase, salt string
+		iter             int
+		expected         string
+	}{
+		{"passwd", "salt", 1, "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc"},
+		{"Password", "NaCl", 80000, "4ddcd8f60b98be21830cee5ef22701f9641a4418d04c0414aeff08876b34ab56"},
+	} {
+		got := hex.EncodeToString(pbkdf2([]byte(tt.passphrase), []byte(tt.salt), tt.iter))
+		if got != tt.expected {
+			t.Errorf("PBKDF2(%q, %q, %d) = %s, expected %s", tt.passphrase, tt.salt, tt.iter, got, tt.expected)
+		}
+	}
+}
+
+func TestOpenCheckpoint(t *testing.T) {
+	defer func(n int) { checkpointIterations = n }(checkpointIterations)
+	checkpointIterations = 1000
+
+	dir, err := ioutil.TempDir("", "powersoftau")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "checkpoint")
+
+	challengeHash := make([]byte, blake2b.Size)
+	passphrase := []byte("hunter2")
+	pub, priv := fixedKeypair()(challengeHash)
+	cp, err := createCheckpoint(filename, challengeHash, passphrase, pub, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cp.update(3, 7); err != nil {
+		t.Fatal(err)
+	}
+	cp.Close()
+
+	file, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range [][]byte{priv.Tau, priv.Alpha, priv.Beta} {
+		if bytes.Contains(file, s) {
+			t.Error("the private key is stored in plaintext")
+		}
+	}
+
+	cp, _, got, section, done, err := openCheckpoint(filename, challengeHash, passphrase, testParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cp.Close()
+	if !bytes.Equal(got.Tau, priv.Tau) || !bytes.Equal(got.Alpha, priv.Alpha) || !bytes.Equal(got.Beta, priv.Beta) {
+		t.Error("wrong private key")
+	}
+	if section != 3 || done != 7 {
+		t.Errorf("got progress %d, %d, expected 3, 7", section, done)
+	}
+
+	if _, _, _, _, _, err := openCheckpoint(filename, challengeHash, []byte("hunter3"), testParams); err == nil ||
+		!strings.Contains(err.Error(), "passphrase") {
+		t.Errorf("opened with the wrong passphrase: %v", err)
+	}
+
+	cp, _, _, _, _, err = openCheckpoint(filename, challengeHash, passphrase, testParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cp.erase(); err != nil {
+		t.Fatal(err)
+	}
+	if err := cp.Close(); err != nil {
+		t.Errorf("Close after erase: %v", err)
+	}
+	if _, err := os.Stat(filename); !os.IsNotExist(err) {
+		t.Errorf("the checkpoint was not removed: %v", err)
+	}
+
+	// Progress outside the accumulator, with a valid checksum.
+	for _, p := range []struct {
+		section uint32
+		done    uint64
+	}{{5, 0}, {1<<32 - 1, 0}, {4, 2}, {0, uint64(testParams.TauPowersG1) + 1}, {0, 1<<64 - 1}} {
+		progress := make([]byte, 12, 20)
+		binary.BigEndian.PutUint32(progress, p.section)
+		binary.BigEndian.PutUint64(progress[4:], p.done)
+		header := file[:checkpointHeaderSize]
+		progress = append(progress, checkpointChecksum(header, progress)...)
+		if err := ioutil.WriteFile(filename, append(append([]byte{}, header...), progress...), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, _, _, _, err := openCheckpoint(filename, challengeHash, passphrase, testParams); err == nil {
+			t.Errorf("opened a checkpoint at section %d, point %d", p.section, p.done)
+		}
+	}
+}
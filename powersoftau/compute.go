@@ -1,41 +1,141 @@
 package powersoftau
 
 import (
-	"math/big"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
 	"sync"
 
 	"github.com/FiloSottile/powersoftau/bls12"
 )
 
-func (c *Challenge) Compute(processes int) {
-	pub, priv := NewKeypair(c.ChallengeHash[:])
-	c.PublicKey = pub
+// Compute multiplies the accumulator by a new random keypair. It returns an
+// error if any point is not in the prime order subgroup, in which case the
+// accumulator is unchanged.
+func (c *Challenge) Compute(processes int) error {
+	return c.ComputeWith(processes, NewKeypair)
+}
+
+// ComputeWith is like Compute, but generates the keypair with newKeypair,
+// for example one returned by BeaconKeypair. It also returns an error if the
+// private key is invalid, in which case the accumulator is unchanged.
+func (c *Challenge) ComputeWith(processes int, newKeypair func(digest []byte) (*PublicKey, *PrivateKey)) error {
+	return c.ComputeWithContext(context.Background(), processes, newKeypair)
+}
 
-	r := (&big.Int{}).SetBytes(bls12.ScalarOrder())
+// ComputeContext is like Compute, but stops the workers and returns
+// ctx.Err() when ctx is canceled. In that case the accumulator is left
+// partially updated, and must be discarded.
+func (c *Challenge) ComputeContext(ctx context.Context, workers int) error {
+	return c.ComputeWithContext(ctx, workers, NewKeypair)
+}
+
+// ComputeWithContext is like ComputeContext, but generates the keypair with
+// newKeypair, like ComputeWith.
+func (c *Challenge) ComputeWithContext(ctx context.Context, processes int, newKeypair func(digest []byte) (*PublicKey, *PrivateKey)) error {
+	// ScalarMultConstantTime is only correct in the subgroup, and the
+	// challenge might have been read without checking it.
+	if err := c.Accumulator.checkSubgroup(); err != nil {
+		return err
+	}
 
-	tau, alpha, beta := &big.Int{}, &big.Int{}, &big.Int{}
-	tau.SetBytes(priv.Tau)
-	alpha.SetBytes(priv.Alpha)
-	beta.SetBytes(priv.Beta)
+	pub, priv := newKeypair(c.ChallengeHash[:])
+	defer priv.Destroy()
 
+	tau, alpha, beta, err := priv.scalars()
+	if err != nil {
+		return err
+	}
+	defer tau.SetZero()
+	defer alpha.SetZero()
+	defer beta.SetZero()
+
+	progress := newProgress(c.Progress, int64(c.Parameters.TauPowersG1))
 	computeRange := func(a, b int) {
-		k, ka, kb := &big.Int{}, &big.Int{}, &big.Int{}
-		k.Exp(tau, big.NewInt(int64(a)), r)
+		var k, ka, kb bls12.Fr
+		defer k.SetZero()
+		defer ka.SetZero()
+		defer kb.SetZero()
+		var buf [32]byte
+		defer ZeroBytes(buf[:])
+		k.Set(tau).Exp(exponent(a))
 
 		for i := a; i < b; i++ {
-			c.Accumulator.TauG1[i].ScalarMult(k.Bytes())
-			if i < TauPowers {
-				c.Accumulator.TauG2[i].ScalarMult(k.Bytes())
-				ka.Mul(k, alpha).Mod(ka, r)
-				c.Accumulator.AlphaTau[i].ScalarMult(ka.Bytes())
-				kb.Mul(k, beta).Mod(kb, r)
-				c.Accumulator.BetaTau[i].ScalarMult(kb.Bytes())
+			c.Accumulator.TauG1[i].ScalarMultConstantTime(k.Bytes(&buf))
+			if i < c.Parameters.TauPowers {
+				c.Accumulator.TauG2[i].ScalarMultConstantTime(k.Bytes(&buf))
+				ka.Set(&k).Mul(alpha)
+				c.Accumulator.AlphaTau[i].ScalarMultConstantTime(ka.Bytes(&buf))
+				kb.Set(&k).Mul(beta)
+				c.Accumulator.BetaTau[i].ScalarMultConstantTime(kb.Bytes(&buf))
 			}
 
-			k.Mul(k, tau).Mod(k, r)
+			k.Mul(tau)
+		}
+
+		// Normalize the chunk now, in parallel, rather than one point at a
+		// time while writing.
+		bls12.NormalizeBatchG1(c.Accumulator.TauG1[a:b])
+		if a < c.Parameters.TauPowers {
+			e := b
+			if e > c.Parameters.TauPowers {
+				e = c.Parameters.TauPowers
+			}
+			bls12.NormalizeBatchG2(c.Accumulator.TauG2[a:e])
+			bls12.NormalizeBatchG1(c.Accumulator.AlphaTau[a:e])
+			bls12.NormalizeBatchG1(c.Accumulator.BetaTau[a:e])
 		}
+		progress.add(int64(b - a))
 	}
 
+	if err := parallelizeContext(ctx, c.Parameters.TauPowersG1, processes, computeRange); err != nil {
+		return err
+	}
+
+	c.Accumulator.BetaG2.ScalarMultConstantTime(priv.Beta)
+	c.PublicKey = pub
+	return nil
+}
+
+// checkSubgroup returns an error naming the first point of the accumulator
+// that is not in the prime order subgroup, if any.
+func (a *Accumulator) checkSubgroup() error {
+	for _, s := range []struct {
+		name   string
+		points []*bls12.EP
+	}{{"TauG1", a.TauG1}, {"AlphaTau", a.AlphaTau}, {"BetaTau", a.BetaTau}} {
+		if i := firstFailure(len(s.points), func(i int) bool { return s.points[i].IsInSubgroup() }); i >= 0 {
+			return fmt.Errorf("invalid G1 point %s[%d]: not in the prime order subgroup", s.name, i)
+		}
+	}
+	if i := firstFailure(len(a.TauG2), func(i int) bool { return a.TauG2[i].IsInSubgroup() }); i >= 0 {
+		return fmt.Errorf("invalid G2 point TauG2[%d]: not in the prime order subgroup", i)
+	}
+	if !a.BetaG2.IsInSubgroup() {
+		return errors.New("invalid G2 point BetaG2[0]: not in the prime order subgroup")
+	}
+	return nil
+}
+
+// exponent encodes the index of a power as an exponent for Fr.Exp.
+func exponent(i int) []byte {
+	e := make([]byte, 8)
+	binary.BigEndian.PutUint64(e, uint64(i))
+	return e
+}
+
+// Parallelize splits [0, n) in chunks of 1<<10 elements, like ChunkEnd, and
+// runs f on them from processes goroutines, returning once all chunks are
+// done.
+func Parallelize(n, processes int, f func(a, b int)) {
+	parallelizeContext(context.Background(), n, processes, f)
+}
+
+// parallelizeContext is like Parallelize, but stops handing out chunks when
+// ctx is canceled, and then returns ctx.Err() once the running ones are
+// done.
+func parallelizeContext(ctx context.Context, n, processes int, f func(a, b int)) error {
 	chunk := 1 << 10
 	work := make(chan struct{ a, b int })
 
@@ -44,21 +144,25 @@ func (c *Challenge) Compute(processes int) {
 		wg.Add(1)
 		go func() {
 			for job := range work {
-				computeRange(job.a, job.b)
+				f(job.a, job.b)
 			}
 			wg.Done()
 		}()
 	}
 
-	for i := 0; i < TauPowersG1; i += chunk {
+loop:
+	for i := 0; i < n; i += chunk {
 		a, b := i, i+chunk
-		if b > TauPowersG1 {
-			b = TauPowersG1
+		if b > n {
+			b = n
+		}
+		select {
+		case work <- struct{ a, b int }{a, b}:
+		case <-ctx.Done():
+			break loop
 		}
-		work <- struct{ a, b int }{a, b}
 	}
 	close(work)
 	wg.Wait()
-
-	c.Accumulator.BetaG2.ScalarMult(priv.Beta)
+	return ctx.Err()
 }
@@ -1,64 +1,79 @@
 package powersoftau
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"io"
 	"os"
+	"runtime"
+	"sync"
 
 	"github.com/FiloSottile/powersoftau/bls12"
 	"golang.org/x/crypto/blake2b"
 )
 
-var (
-	TauPowers     = 1 << 21
-	TauPowersG1   = TauPowers<<1 - 1
-	ChallengeSize = TauPowersG1*bls12.G1UncompressedSize + // G1 powers
-		TauPowers*bls12.G2UncompressedSize + // G2 powers
-		TauPowers*bls12.G1UncompressedSize + // alpha powers
-		TauPowers*bls12.G1UncompressedSize + // beta powers
-		bls12.G2UncompressedSize + // beta
-		blake2b.Size
-	PublicKeySize = 3*bls12.G2UncompressedSize + 6*bls12.G1UncompressedSize
-	ResponseSize  = TauPowersG1*bls12.G1CompressedSize + // G1 powers
-		TauPowers*bls12.G2CompressedSize + // G2 powers
-		TauPowers*bls12.G1CompressedSize + // alpha powers
-		TauPowers*bls12.G1CompressedSize + // beta powers
-		bls12.G2CompressedSize + // beta
-		blake2b.Size + PublicKeySize
-)
-
 type Challenge struct {
 	PreviousHash  []byte
 	ChallengeHash []byte
 	ResponseHash  []byte
 
+	Parameters  *Parameters
 	Accumulator *Accumulator
 	PublicKey   *PublicKey
+
+	// Progress, if not nil, is called by Compute, WriteResponse and
+	// WriteNextChallenge as they make progress.
+	Progress ProgressFunc
+}
+
+// ReadChallenge reads a challenge file. If params is nil, they are
+// detected from the file size. If checkSubgroup is true, all points are
+// checked to be in the prime order subgroup, which is as expensive as a
+// contribution, and only necessary for untrusted inputs.
+func ReadChallenge(filename string, params *Parameters, checkSubgroup bool) (*Challenge, error) {
+	return ReadChallengeProgress(filename, params, checkSubgroup, nil)
+}
+
+// ReadChallengeProgress is like ReadChallenge, but calls progress as the
+// file is read.
+func ReadChallengeProgress(filename string, params *Parameters, checkSubgroup bool, progress ProgressFunc) (*Challenge, error) {
+	return ReadChallengeContext(context.Background(), filename, params, checkSubgroup, progress)
 }
 
-func ReadChallenge(filename string) (*Challenge, error) {
+// ReadChallengeContext is like ReadChallengeProgress, but stops with
+// ctx.Err() when ctx is canceled.
+func ReadChallengeContext(ctx context.Context, filename string, params *Parameters, checkSubgroup bool, progress ProgressFunc) (*Challenge, error) {
 	fi, err := os.Stat(filename)
 	if err != nil {
 		return nil, err
 	}
-	if fi.Size() != int64(ChallengeSize) {
+	if params == nil {
+		params, err = ChallengeParameters(fi.Size())
+		if err != nil {
+			return nil, err
+		}
+	}
+	if fi.Size() != params.ChallengeSize {
 		return nil, errors.New("the challenge file has the wrong size")
 	}
 	f, err := os.Open(filename)
 	if err != nil {
 		return nil, err
 	}
+	defer f.Close()
 
 	h, _ := blake2b.New512(nil)
-	r := io.TeeReader(f, h)
+	r := io.TeeReader(progressReader{contextReader{ctx, f}, newProgress(progress, params.ChallengeSize)}, h)
 
 	c := &Challenge{
 		PreviousHash: make([]byte, blake2b.Size),
+		Parameters:   params,
 	}
 	if _, err := io.ReadFull(r, c.PreviousHash); err != nil {
 		return nil, err
 	}
-	c.Accumulator, err = ReadAccumulator(r, false)
+	c.Accumulator, err = ReadAccumulator(r, params, false, checkSubgroup)
 	if err != nil {
 		return nil, err
 	}
@@ -67,43 +82,118 @@ func ReadChallenge(filename string) (*Challenge, error) {
 	return c, nil
 }
 
-func WriteResponse(filename string, ch *Challenge) error {
-	f, err := os.Create(filename)
+// ReadResponse reads a response file. The returned Challenge has
+// ChallengeHash set to the hash the response claims to be based on, and
+// ResponseHash to the hash of the response file itself. params and
+// checkSubgroup work like for ReadChallenge.
+func ReadResponse(filename string, params *Parameters, checkSubgroup bool) (*Challenge, error) {
+	return ReadResponseProgress(filename, params, checkSubgroup, nil)
+}
+
+// ReadResponseProgress is like ReadResponse, but calls progress as the file
+// is read.
+func ReadResponseProgress(filename string, params *Parameters, checkSubgroup bool, progress ProgressFunc) (*Challenge, error) {
+	return ReadResponseContext(context.Background(), filename, params, checkSubgroup, progress)
+}
+
+// ReadResponseContext is like ReadResponseProgress, but stops with
+// ctx.Err() when ctx is canceled.
+func ReadResponseContext(ctx context.Context, filename string, params *Parameters, checkSubgroup bool, progress ProgressFunc) (*Challenge, error) {
+	fi, err := os.Stat(filename)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	if params == nil {
+		params, err = ResponseParameters(fi.Size())
+		if err != nil {
+			return nil, err
+		}
+	}
+	if fi.Size() != params.ResponseSize {
+		return nil, errors.New("the response file has the wrong size")
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
 
 	h, _ := blake2b.New512(nil)
-	w := io.MultiWriter(f, h)
+	r := io.TeeReader(progressReader{contextReader{ctx, f}, newProgress(progress, params.ResponseSize)}, h)
 
-	if _, err := w.Write(ch.ChallengeHash); err != nil {
-		return err
+	c := &Challenge{
+		ChallengeHash: make([]byte, blake2b.Size),
+		Parameters:    params,
 	}
-	if err := ch.Accumulator.WriteTo(w, true); err != nil {
+	if _, err := io.ReadFull(r, c.ChallengeHash); err != nil {
+		return nil, err
+	}
+	c.Accumulator, err = ReadAccumulator(r, params, true, checkSubgroup)
+	if err != nil {
+		return nil, err
+	}
+	c.PublicKey, err = ReadPublicKey(r, checkSubgroup)
+	if err != nil {
+		return nil, err
+	}
+	c.ResponseHash = h.Sum(nil)
+
+	return c, nil
+}
+
+// WriteResponse writes ch as a response file, and sets ch.ResponseHash.
+// The accumulator must match ch.Parameters.
+//
+// The file is first written under a temporary name, and then renamed, so
+// that a partial response is never left behind.
+func WriteResponse(filename string, ch *Challenge) error {
+	return WriteResponseContext(context.Background(), filename, ch)
+}
+
+// WriteResponseContext is like WriteResponse, but stops with ctx.Err() when
+// ctx is canceled.
+func WriteResponseContext(ctx context.Context, filename string, ch *Challenge) error {
+	if err := ch.Parameters.check(ch.Accumulator); err != nil {
 		return err
 	}
-	if err := ch.PublicKey.WriteTo(w); err != nil {
+	h, _ := blake2b.New512(nil)
+	err := WriteFileAtomic(ctx, filename, func(w io.Writer) error {
+		w = progressWriter{io.MultiWriter(w, h), newProgress(ch.Progress, ch.Parameters.ResponseSize)}
+		if _, err := w.Write(ch.ChallengeHash); err != nil {
+			return err
+		}
+		if err := ch.Accumulator.WriteTo(w, true); err != nil {
+			return err
+		}
+		return ch.PublicKey.WriteTo(w)
+	})
+	if err != nil {
 		return err
 	}
-
 	ch.ResponseHash = h.Sum(nil)
 	return nil
 }
 
+// WriteNextChallenge writes the accumulator of ch as a new challenge
+// file, based on ch.ResponseHash. The accumulator must match ch.Parameters.
+// Like WriteResponse, it never leaves a partial file behind.
 func WriteNextChallenge(filename string, ch *Challenge) error {
-	f, err := os.Create(filename)
-	if err != nil {
-		return err
-	}
+	return WriteNextChallengeContext(context.Background(), filename, ch)
+}
 
-	if _, err := f.Write(ch.ResponseHash); err != nil {
+// WriteNextChallengeContext is like WriteNextChallenge, but stops with
+// ctx.Err() when ctx is canceled.
+func WriteNextChallengeContext(ctx context.Context, filename string, ch *Challenge) error {
+	if err := ch.Parameters.check(ch.Accumulator); err != nil {
 		return err
 	}
-	if err := ch.Accumulator.WriteTo(f, false); err != nil {
-		return err
-	}
-
-	return nil
+	return WriteFileAtomic(ctx, filename, func(w io.Writer) error {
+		w = progressWriter{w, newProgress(ch.Progress, ch.Parameters.ChallengeSize)}
+		if _, err := w.Write(ch.ResponseHash); err != nil {
+			return err
+		}
+		return ch.Accumulator.WriteTo(w, false)
+	})
 }
 
 type Accumulator struct {
@@ -114,34 +204,40 @@ type Accumulator struct {
 	BetaG2   *bls12.EP2
 }
 
-func ReadAccumulator(r io.Reader, compressed bool) (*Accumulator, error) {
+func ReadAccumulator(r io.Reader, params *Parameters, compressed, checkSubgroup bool) (*Accumulator, error) {
 	a := &Accumulator{}
 	var err error
-	a.TauG1, err = readG1Slice(r, TauPowersG1, compressed)
+	a.TauG1, err = readG1Slice(r, "TauG1", params.TauPowersG1, compressed, checkSubgroup)
 	if err != nil {
 		return nil, err
 	}
-	a.TauG2, err = readG2Slice(r, TauPowers, compressed)
+	a.TauG2, err = readG2Slice(r, "TauG2", params.TauPowers, compressed, checkSubgroup)
 	if err != nil {
 		return nil, err
 	}
-	a.AlphaTau, err = readG1Slice(r, TauPowers, compressed)
+	a.AlphaTau, err = readG1Slice(r, "AlphaTau", params.TauPowers, compressed, checkSubgroup)
 	if err != nil {
+		closeG2s(a.TauG2)
 		return nil, err
 	}
-	a.BetaTau, err = readG1Slice(r, TauPowers, compressed)
+	a.BetaTau, err = readG1Slice(r, "BetaTau", params.TauPowers, compressed, checkSubgroup)
 	if err != nil {
+		closeG2s(a.TauG2)
 		return nil, err
 	}
-	pp, err := readG2Slice(r, 1, compressed)
+	pp, err := readG2Slice(r, "BetaG2", 1, compressed, checkSubgroup)
 	if err != nil {
+		closeG2s(a.TauG2)
 		return nil, err
 	}
 	a.BetaG2 = pp[0]
 	return a, nil
 }
 
-func readG1Slice(r io.Reader, n int, compressed bool) ([]*bls12.EP, error) {
+// readG1Slice reads n points. name is used to identify the
+// offending point in errors. If checkSubgroup is true, the points are
+// checked to be in the prime order subgroup with all available CPUs.
+func readG1Slice(r io.Reader, name string, n int, compressed, checkSubgroup bool) ([]*bls12.EP, error) {
 	var buf []byte
 	if compressed {
 		buf = make([]byte, bls12.G1CompressedSize)
@@ -161,14 +257,19 @@ func readG1Slice(r io.Reader, n int, compressed bool) ([]*bls12.EP, error) {
 			p, err = p.DecodeUncompressed(buf)
 		}
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("invalid G1 point %s[%d]: %v", name, i, err)
 		}
 		res = append(res, p)
 	}
+	if checkSubgroup {
+		if i := firstFailure(n, func(i int) bool { return res[i].IsInSubgroup() }); i >= 0 {
+			return nil, fmt.Errorf("invalid G1 point %s[%d]: not in the prime order subgroup", name, i)
+		}
+	}
 	return res, nil
 }
 
-func readG2Slice(r io.Reader, n int, compressed bool) ([]*bls12.EP2, error) {
+func readG2Slice(r io.Reader, name string, n int, compressed, checkSubgroup bool) ([]*bls12.EP2, error) {
 	var buf []byte
 	if compressed {
 		buf = make([]byte, bls12.G2CompressedSize)
@@ -178,23 +279,96 @@ func readG2Slice(r io.Reader, n int, compressed bool) ([]*bls12.EP2, error) {
 	var res []*bls12.EP2
 	for i := 0; i < n; i++ {
 		if _, err := io.ReadFull(r, buf); err != nil {
+			closeG2s(res)
 			return nil, err
 		}
 		p := bls12.NewEP2()
 		var err error
 		if compressed {
-			p, err = p.DecodeCompressed(buf)
+			_, err = p.DecodeCompressed(buf)
 		} else {
-			p, err = p.DecodeUncompressed(buf)
+			_, err = p.DecodeUncompressed(buf)
 		}
 		if err != nil {
-			return nil, err
+			p.Close()
+			closeG2s(res)
+			return nil, fmt.Errorf("invalid G2 point %s[%d]: %v", name, i, err)
 		}
 		res = append(res, p)
 	}
+	if checkSubgroup {
+		if i := firstFailure(n, func(i int) bool { return res[i].IsInSubgroup() }); i >= 0 {
+			closeG2s(res)
+			return nil, fmt.Errorf("invalid G2 point %s[%d]: not in the prime order subgroup", name, i)
+		}
+	}
 	return res, nil
 }
 
+// closeG2s frees the points of a slice that is being discarded, for example
+// because a later point failed to decode.
+func closeG2s(s []*bls12.EP2) {
+	for _, p := range s {
+		p.Close()
+	}
+}
+
+// ReadG1 reads a single uncompressed G1 point. If checkSubgroup is true,
+// the point is checked to be in the prime order subgroup.
+func ReadG1(r io.Reader, checkSubgroup bool) (*bls12.EP, error) {
+	buf := make([]byte, bls12.G1UncompressedSize)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	p, err := (&bls12.EP{}).DecodeUncompressed(buf)
+	if err != nil {
+		return nil, fmt.Errorf("invalid G1 point: %v", err)
+	}
+	if checkSubgroup && !p.IsInSubgroup() {
+		return nil, errors.New("invalid G1 point: not in the prime order subgroup")
+	}
+	return p, nil
+}
+
+// ReadG2 is like ReadG1, but for G2. The point is freed on error, and
+// otherwise the caller must Close it.
+func ReadG2(r io.Reader, checkSubgroup bool) (*bls12.EP2, error) {
+	buf := make([]byte, bls12.G2UncompressedSize)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	p := bls12.NewEP2()
+	if _, err := p.DecodeUncompressed(buf); err != nil {
+		p.Close()
+		return nil, fmt.Errorf("invalid G2 point: %v", err)
+	}
+	if checkSubgroup && !p.IsInSubgroup() {
+		p.Close()
+		return nil, errors.New("invalid G2 point: not in the prime order subgroup")
+	}
+	return p, nil
+}
+
+// firstFailure runs check on [0, n) with all available CPUs, and returns
+// the lowest index for which it returned false, or -1.
+func firstFailure(n int, check func(i int) bool) int {
+	first := -1
+	var mu sync.Mutex
+	Parallelize(n, runtime.NumCPU(), func(a, b int) {
+		for i := a; i < b; i++ {
+			if !check(i) {
+				mu.Lock()
+				if first < 0 || i < first {
+					first = i
+				}
+				mu.Unlock()
+				return
+			}
+		}
+	})
+	return first
+}
+
 func (a *Accumulator) WriteTo(w io.Writer, compressed bool) error {
 	if err := writeG1Slice(w, a.TauG1, compressed); err != nil {
 		return err
@@ -214,8 +388,14 @@ func (a *Accumulator) WriteTo(w io.Writer, compressed bool) error {
 	return nil
 }
 
+// writeG1Slice encodes the points of s to w. Points that are not already
+// affine, for example because they were not computed by Compute, are
+// normalized in batches.
 func writeG1Slice(w io.Writer, s []*bls12.EP, compressed bool) error {
-	for _, p := range s {
+	for i, p := range s {
+		if i%(1<<10) == 0 {
+			bls12.NormalizeBatchG1(s[i:ChunkEnd(i, len(s))])
+		}
 		var buf []byte
 		if compressed {
 			buf = p.EncodeCompressed()
@@ -230,7 +410,10 @@ func writeG1Slice(w io.Writer, s []*bls12.EP, compressed bool) error {
 }
 
 func writeG2Slice(w io.Writer, s []*bls12.EP2, compressed bool) error {
-	for _, p := range s {
+	for i, p := range s {
+		if i%(1<<10) == 0 {
+			bls12.NormalizeBatchG2(s[i:ChunkEnd(i, len(s))])
+		}
 		var buf []byte
 		if compressed {
 			buf = p.EncodeCompressed()
@@ -244,6 +427,15 @@ func writeG2Slice(w io.Writer, s []*bls12.EP2, compressed bool) error {
 	return nil
 }
 
+// ChunkEnd returns the end of the chunk of 1<<10 elements starting at i, in
+// a slice of n elements. Points are normalized and written in such chunks.
+func ChunkEnd(i, n int) int {
+	if i+1<<10 > n {
+		return n
+	}
+	return i + 1<<10
+}
+
 func (p *PublicKey) WriteTo(w io.Writer) error {
 	for _, point := range [][]byte{
 		p.Tau.S.EncodeUncompressed(),
@@ -262,3 +454,19 @@ func (p *PublicKey) WriteTo(w io.Writer) error {
 	}
 	return nil
 }
+
+func ReadPublicKey(r io.Reader, checkSubgroup bool) (*PublicKey, error) {
+	g1, err := readG1Slice(r, "PublicKey", 6, false, checkSubgroup)
+	if err != nil {
+		return nil, err
+	}
+	g2, err := readG2Slice(r, "PublicKey", 3, false, checkSubgroup)
+	if err != nil {
+		return nil, err
+	}
+	p := &PublicKey{}
+	p.Tau.S, p.Tau.Sx, p.Tau.SxG2x = g1[0], g1[1], g2[0]
+	p.Alpha.S, p.Alpha.Sx, p.Alpha.SxG2x = g1[2], g1[3], g2[1]
+	p.Beta.S, p.Beta.Sx, p.Beta.SxG2x = g1[4], g1[5], g2[2]
+	return p, nil
+}
@@ -0,0 +1,157 @@
+package powersoftau
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/FiloSottile/powersoftau/bls12"
+	"golang.org/x/crypto/blake2b"
+)
+
+// An EntropySource provides the randomness the private key is derived from.
+type EntropySource interface {
+	// Entropy returns some random bytes. How many is up to the source.
+	Entropy() ([]byte, error)
+}
+
+// SystemEntropy reads 64 bytes from crypto/rand, the OS RNG.
+type SystemEntropy struct{}
+
+func (SystemEntropy) Entropy() ([]byte, error) {
+	b := make([]byte, 64)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// KeyboardEntropy asks the participant to type some random text.
+type KeyboardEntropy struct {
+	// In and Out default to os.Stdin and os.Stderr.
+	In  io.Reader
+	Out io.Writer
+}
+
+func (k KeyboardEntropy) Entropy() ([]byte, error) {
+	in, out := k.In, k.Out
+	if in == nil {
+		in = os.Stdin
+	}
+	if out == nil {
+		out = os.Stderr
+	}
+	fmt.Fprintf(out, "Type some random text and press [ENTER] to provide additional entropy...\n")
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && !(err == io.EOF && line != "") {
+		return nil, fmt.Errorf("failed to read keyboard entropy: %v", err)
+	}
+	if strings.TrimSpace(line) == "" {
+		return nil, errors.New("no keyboard entropy was typed")
+	}
+	return []byte(line), nil
+}
+
+// FileEntropy reads Size bytes from the file at Path, for example a device
+// file exposed by a hardware RNG. If Size is zero, 64 bytes are read.
+type FileEntropy struct {
+	Path string
+	Size int
+}
+
+func (f FileEntropy) Entropy() ([]byte, error) {
+	size := f.Size
+	if size == 0 {
+		size = 64
+	}
+	file, err := os.Open(f.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	b := make([]byte, size)
+	if _, err := io.ReadFull(file, b); err != nil {
+		return nil, fmt.Errorf("failed to read entropy from %s: %v", f.Path, err)
+	}
+	return b, nil
+}
+
+// CombinedEntropy hashes together the entropy of all its sources with
+// BLAKE2b, so that the result is unpredictable as long as any one of
+// them is.
+type CombinedEntropy []EntropySource
+
+func (c CombinedEntropy) Entropy() ([]byte, error) {
+	if len(c) == 0 {
+		return nil, errors.New("no entropy sources")
+	}
+	h, _ := blake2b.New512(nil)
+	for _, s := range c {
+		b, err := s.Entropy()
+		if err != nil {
+			return nil, err
+		}
+		var l [8]byte
+		binary.BigEndian.PutUint64(l[:], uint64(len(b)))
+		h.Write(l[:])
+		h.Write(b)
+		ZeroBytes(b)
+	}
+	return h.Sum(nil), nil
+}
+
+// ParseEntropySource parses a comma-separated list of sources: "system"
+// for SystemEntropy, "keyboard" for KeyboardEntropy, and "file:PATH" for
+// FileEntropy. Multiple sources are combined with CombinedEntropy.
+func ParseEntropySource(spec string) (EntropySource, error) {
+	var c CombinedEntropy
+	for _, s := range strings.Split(spec, ",") {
+		switch s = strings.TrimSpace(s); {
+		case s == "system":
+			c = append(c, SystemEntropy{})
+		case s == "keyboard":
+			c = append(c, KeyboardEntropy{})
+		case strings.HasPrefix(s, "file:") && len(s) > len("file:"):
+			c = append(c, FileEntropy{Path: strings.TrimPrefix(s, "file:")})
+		default:
+			return nil, fmt.Errorf("unknown entropy source %q", s)
+		}
+	}
+	return c, nil
+}
+
+// EntropyKeypair collects entropy from source, and returns a NewKeypair
+// replacement that derives the keypair from it.
+//
+// Like the Rust compute binary, the entropy is hashed with BLAKE2b, and the
+// hash seeds a ChaCha20 RNG, from which tau, alpha, beta and the proofs of
+// knowledge are sampled by rejection against ScalarOrder.
+//
+// The entropy is collected immediately, so that any interaction with the
+// participant happens before the computation starts.
+func EntropyKeypair(source EntropySource) (func(digest []byte) (*PublicKey, *PrivateKey), error) {
+	b, err := source.Entropy()
+	if err != nil {
+		return nil, err
+	}
+	seed := blake2b.Sum512(b)
+	ZeroBytes(b)
+	used := false
+	return func(digest []byte) (*PublicKey, *PrivateKey) {
+		if used {
+			panic("powersoftau: EntropyKeypair function called twice")
+		}
+		used = true
+		rng := newRustRng(seed[:])
+		ZeroBytes(seed[:])
+		scalar := func() *bls12.Fr { return readScalar(rng) }
+		return newKeypair(digest, scalar, func() *bls12.EP {
+			return randomG1(scalar())
+		})
+	}, nil
+}
@@ -0,0 +1,134 @@
+package powersoftau
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestKeyboardEntropy(t *testing.T) {
+	out := &bytes.Buffer{}
+	b, err := KeyboardEntropy{In: strings.NewReader("hello world\nignored"), Out: out}.Entropy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "hello world\n" {
+		t.Errorf("got %q", b)
+	}
+	if out.Len() == 0 {
+		t.Error("no prompt was printed")
+	}
+	if _, err := (KeyboardEntropy{In: strings.NewReader("  \n"), Out: out}).Entropy(); err == nil {
+		t.Error("empty input was accepted")
+	}
+}
+
+func TestFileEntropy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "powersoftau")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "rng")
+	if err := ioutil.WriteFile(path, bytes.Repeat([]byte{42}, 100), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := FileEntropy{Path: path}.Entropy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, bytes.Repeat([]byte{42}, 64)) {
+		t.Errorf("got %x", b)
+	}
+	if _, err := (FileEntropy{Path: path, Size: 101}).Entropy(); err == nil {
+		t.Error("a short read was accepted")
+	}
+}
+
+// fixedEntropy is a deterministic EntropySource for tests.
+type fixedEntropy string
+
+func (f fixedEntropy) Entropy() ([]byte, error) { return []byte(f), nil }
+
+func TestCombinedEntropy(t *testing.T) {
+	a, err := CombinedEntropy{fixedEntropy("a"), fixedEntropy("bc")}.Entropy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := CombinedEntropy{fixedEntropy("a"), fixedEntropy("bc")}.Entropy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(a, b) {
+		t.Error("CombinedEntropy is not deterministic")
+	}
+	c, err := CombinedEntropy{fixedEntropy("ab"), fixedEntropy("c")}.Entropy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(a, c) {
+		t.Error("CombinedEntropy is ambiguous")
+	}
+	if _, err := (CombinedEntropy{}).Entropy(); err == nil {
+		t.Error("no sources were accepted")
+	}
+}
+
+func TestParseEntropySource(t *testing.T) {
+	s, err := ParseEntropySource("system, keyboard,file:/dev/hwrng")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, ok := s.(CombinedEntropy)
+	if !ok || len(c) != 3 {
+		t.Fatalf("got %#v", s)
+	}
+	if f, ok := c[2].(FileEntropy); !ok || f.Path != "/dev/hwrng" {
+		t.Errorf("got %#v", c[2])
+	}
+	for _, spec := range []string{"", "system,", "file:", "dice"} {
+		if _, err := ParseEntropySource(spec); err == nil {
+			t.Errorf("%q was accepted", spec)
+		}
+	}
+}
+
+func TestEntropyKeypair(t *testing.T) {
+	digest := make([]byte, 64)
+	newKeypair, err := EntropyKeypair(fixedEntropy("toxic waste"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, priv := newKeypair(digest)
+	if err := pub.Verify(digest); err != nil {
+		t.Fatal(err)
+	}
+
+	again, err := EntropyKeypair(fixedEntropy("toxic waste"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub2, priv2 := again(digest)
+	if !bytes.Equal(priv.Tau, priv2.Tau) || !pub.Beta.S.Equal(pub2.Beta.S) {
+		t.Error("the keypair is not derived from the entropy")
+	}
+
+	other, err := EntropyKeypair(fixedEntropy("other waste"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, priv3 := other(digest); bytes.Equal(priv.Tau, priv3.Tau) {
+		t.Error("different entropy produced the same keypair")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("the keypair function could be called twice")
+		}
+	}()
+	newKeypair(digest)
+}
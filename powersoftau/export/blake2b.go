@@ -0,0 +1,167 @@
+package export
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/bits"
+)
+
+// blake2bState is a BLAKE2b-512 hash which, unlike golang.org/x/crypto/blake2b,
+// can export and resume its internal state. snarkjs stores that state as the
+// partialHash of a contribution, so that the response hash can be finished
+// from the public key. The serialization follows the blake2b-wasm context
+// layout: the current block, the chaining value, the 128-bit byte counter,
+// the 32-bit number of bytes in the current block, and the 32-bit output
+// length, all little-endian.
+type blake2bState struct {
+	b [128]byte
+	h [8]uint64
+	t [2]uint64
+	c int
+}
+
+// partialHashSize is the size of a serialized blake2bState.
+const partialHashSize = 216
+
+// blake2bSize is the output length, which is part of the serialized state.
+const blake2bSize = 64
+
+var blake2bIV = [8]uint64{
+	0x6a09e667f3bcc908, 0xbb67ae8584caa73b, 0x3c6ef372fe94f82b, 0xa54ff53a5f1d36f1,
+	0x510e527fade682d1, 0x9b05688c2b3e6c1f, 0x1f83d9abfb41bd6b, 0x5be0cd19137e2179,
+}
+
+var blake2bSigma = [10][16]byte{
+	{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+	{14, 10, 4, 8, 9, 15, 13, 6, 1, 12, 0, 2, 11, 7, 5, 3},
+	{11, 8, 12, 0, 5, 2, 15, 13, 10, 14, 3, 6, 7, 1, 9, 4},
+	{7, 9, 3, 1, 13, 12, 11, 14, 2, 6, 5, 10, 4, 0, 15, 8},
+	{9, 0, 5, 7, 2, 4, 10, 15, 14, 1, 11, 12, 6, 8, 3, 13},
+	{2, 12, 6, 10, 0, 11, 8, 3, 4, 13, 7, 5, 15, 14, 1, 9},
+	{12, 5, 1, 15, 14, 13, 4, 10, 0, 7, 6, 3, 9, 2, 8, 11},
+	{13, 11, 7, 14, 12, 1, 3, 9, 5, 0, 15, 4, 8, 6, 2, 10},
+	{6, 15, 14, 9, 11, 3, 0, 8, 12, 2, 13, 7, 1, 4, 10, 5},
+	{10, 2, 8, 4, 7, 6, 1, 5, 15, 11, 9, 14, 3, 12, 13, 0},
+}
+
+func newBlake2b() *blake2bState {
+	s := &blake2bState{h: blake2bIV}
+	s.h[0] ^= 0x01010000 ^ blake2bSize
+	return s
+}
+
+// Write hashes p. Like the reference implementation, a full block is only
+// compressed once more input arrives, as the last block is special.
+func (s *blake2bState) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		if s.c == len(s.b) {
+			s.increment(uint64(s.c))
+			s.compress(false)
+			s.c = 0
+		}
+		k := copy(s.b[s.c:], p)
+		s.c += k
+		p = p[k:]
+	}
+	return n, nil
+}
+
+// Sum returns the hash of the input so far, without changing the state.
+func (s *blake2bState) Sum() []byte {
+	d := *s
+	d.increment(uint64(d.c))
+	for i := d.c; i < len(d.b); i++ {
+		d.b[i] = 0
+	}
+	d.compress(true)
+	out := make([]byte, blake2bSize)
+	for i, v := range d.h {
+		binary.LittleEndian.PutUint64(out[8*i:], v)
+	}
+	return out
+}
+
+// MarshalPartial returns the serialized state.
+func (s *blake2bState) MarshalPartial() []byte {
+	out := make([]byte, partialHashSize)
+	copy(out, s.b[:])
+	for i, v := range s.h {
+		binary.LittleEndian.PutUint64(out[128+8*i:], v)
+	}
+	binary.LittleEndian.PutUint64(out[192:], s.t[0])
+	binary.LittleEndian.PutUint64(out[200:], s.t[1])
+	binary.LittleEndian.PutUint32(out[208:], uint32(s.c))
+	binary.LittleEndian.PutUint32(out[212:], blake2bSize)
+	return out
+}
+
+// resumeBlake2b returns the state serialized by MarshalPartial.
+func resumeBlake2b(partial []byte) (*blake2bState, error) {
+	if len(partial) != partialHashSize {
+		return nil, errors.New("wrong partial hash size")
+	}
+	s := &blake2bState{}
+	copy(s.b[:], partial)
+	for i := range s.h {
+		s.h[i] = binary.LittleEndian.Uint64(partial[128+8*i:])
+	}
+	s.t[0] = binary.LittleEndian.Uint64(partial[192:])
+	s.t[1] = binary.LittleEndian.Uint64(partial[200:])
+	c := binary.LittleEndian.Uint32(partial[208:])
+	if c > uint32(len(s.b)) {
+		return nil, errors.New("invalid partial hash")
+	}
+	if outlen := binary.LittleEndian.Uint32(partial[212:]); outlen != blake2bSize {
+		return nil, errors.New("partial hash of a BLAKE2b with the wrong output length")
+	}
+	s.c = int(c)
+	return s, nil
+}
+
+func (s *blake2bState) increment(n uint64) {
+	s.t[0] += n
+	if s.t[0] < n {
+		s.t[1]++
+	}
+}
+
+func (s *blake2bState) compress(last bool) {
+	var m [16]uint64
+	for i := range m {
+		m[i] = binary.LittleEndian.Uint64(s.b[8*i:])
+	}
+	var v [16]uint64
+	copy(v[:8], s.h[:])
+	copy(v[8:], blake2bIV[:])
+	v[12] ^= s.t[0]
+	v[13] ^= s.t[1]
+	if last {
+		v[14] = ^v[14]
+	}
+	for i := 0; i < 12; i++ {
+		sg := &blake2bSigma[i%10]
+		blake2bG(&v, 0, 4, 8, 12, m[sg[0]], m[sg[1]])
+		blake2bG(&v, 1, 5, 9, 13, m[sg[2]], m[sg[3]])
+		blake2bG(&v, 2, 6, 10, 14, m[sg[4]], m[sg[5]])
+		blake2bG(&v, 3, 7, 11, 15, m[sg[6]], m[sg[7]])
+		blake2bG(&v, 0, 5, 10, 15, m[sg[8]], m[sg[9]])
+		blake2bG(&v, 1, 6, 11, 12, m[sg[10]], m[sg[11]])
+		blake2bG(&v, 2, 7, 8, 13, m[sg[12]], m[sg[13]])
+		blake2bG(&v, 3, 4, 9, 14, m[sg[14]], m[sg[15]])
+	}
+	for i := range s.h {
+		s.h[i] ^= v[i] ^ v[i+8]
+	}
+}
+
+func blake2bG(v *[16]uint64, a, b, c, d int, x, y uint64) {
+	v[a] += v[b] + x
+	v[d] = bits.RotateLeft64(v[d]^v[a], -32)
+	v[c] += v[d]
+	v[b] = bits.RotateLeft64(v[b]^v[c], -24)
+	v[a] += v[b] + y
+	v[d] = bits.RotateLeft64(v[d]^v[a], -16)
+	v[c] += v[d]
+	v[b] = bits.RotateLeft64(v[b]^v[c], -63)
+}
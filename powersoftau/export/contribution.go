@@ -0,0 +1,268 @@
+package export
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/FiloSottile/powersoftau/bls12"
+	"github.com/FiloSottile/powersoftau/powersoftau"
+	"golang.org/x/crypto/blake2b"
+)
+
+/*
+
+The contributions section is the number of contributions, followed by
+each contribution:
+
+	1. TauG1[1], TauG2[1], AlphaTau[0], BetaTau[0] and BetaG2 after it
+	2. The public key, in the order of PublicKey.WriteTo, with the same
+	   point encoding as the other sections
+	3. The BLAKE2b state after hashing the response file up to the public
+	   key, see blake2bState
+	4. The hash of the next challenge file
+	5. The type, 0 for a normal contribution and 1 for a beacon
+	6. The size of the parameters, and the parameters, each a one byte
+	   key followed by its value:
+		1. The name, as a one byte length and the UTF-8 name
+		2. The base 2 logarithm of the beacon iterations, one byte
+		3. The beacon hash, as a one byte length and the hash
+
+*/
+
+// A Contribution is an entry of the contributions section of a .ptau file.
+type Contribution struct {
+	// TauG1, TauG2, AlphaG1, BetaG1 and BetaG2 are TauG1[1], TauG2[1],
+	// AlphaTau[0], BetaTau[0] and BetaG2 of the accumulator after the
+	// contribution.
+	TauG1   *bls12.EP
+	TauG2   *bls12.EP2
+	AlphaG1 *bls12.EP
+	BetaG1  *bls12.EP
+	BetaG2  *bls12.EP2
+
+	PublicKey *powersoftau.PublicKey
+
+	// PartialHash is the BLAKE2b state after hashing the response file up
+	// to the public key. ResponseHash finishes it.
+	PartialHash []byte
+
+	// NextChallenge is the BLAKE2b hash of the challenge file made from
+	// the response.
+	NextChallenge []byte
+
+	// Name optionally describes the contributor. snarkjs truncates it to
+	// 64 characters.
+	Name string
+
+	// Beacon is true for a random beacon contribution, made with
+	// BeaconHash hashed 2^BeaconIterations times.
+	Beacon           bool
+	BeaconHash       []byte
+	BeaconIterations uint
+}
+
+// NewContribution returns the Contribution for a response, as read by
+// powersoftau.ReadResponse.
+func NewContribution(resp *powersoftau.Challenge) (*Contribution, error) {
+	if resp.PublicKey == nil || resp.ChallengeHash == nil {
+		return nil, errors.New("not a response")
+	}
+	a := resp.Accumulator
+	if len(a.TauG1) < 2 || len(a.TauG2) < 2 {
+		return nil, errors.New("the accumulator is too small")
+	}
+
+	h := newBlake2b()
+	h.Write(resp.ChallengeHash)
+	if err := a.WriteTo(h, true); err != nil {
+		return nil, err
+	}
+	c := &Contribution{
+		TauG1:       a.TauG1[1],
+		TauG2:       a.TauG2[1],
+		AlphaG1:     a.AlphaTau[0],
+		BetaG1:      a.BetaTau[0],
+		BetaG2:      a.BetaG2,
+		PublicKey:   resp.PublicKey,
+		PartialHash: h.MarshalPartial(),
+	}
+
+	responseHash, err := c.ResponseHash()
+	if err != nil {
+		return nil, err
+	}
+	if resp.ResponseHash != nil && !bytes.Equal(responseHash, resp.ResponseHash) {
+		return nil, errors.New("the response hash does not match the response")
+	}
+	next, _ := blake2b.New512(nil)
+	next.Write(responseHash)
+	if err := a.WriteTo(next, false); err != nil {
+		return nil, err
+	}
+	c.NextChallenge = next.Sum(nil)
+	return c, nil
+}
+
+// ResponseHash returns the BLAKE2b hash of the response file, computed
+// from PartialHash and PublicKey.
+func (c *Contribution) ResponseHash() ([]byte, error) {
+	h, err := resumeBlake2b(c.PartialHash)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.PublicKey.WriteTo(h); err != nil {
+		return nil, err
+	}
+	return h.Sum(), nil
+}
+
+func (c *Contribution) writeTo(b *bytes.Buffer) error {
+	if len(c.PartialHash) != partialHashSize || len(c.NextChallenge) != blake2b.Size {
+		return errors.New("invalid contribution hashes")
+	}
+	putG1(b, c.TauG1)
+	putG2(b, c.TauG2)
+	putG1(b, c.AlphaG1)
+	putG1(b, c.BetaG1)
+	putG2(b, c.BetaG2)
+	pk := c.PublicKey
+	for _, p := range []*bls12.EP{pk.Tau.S, pk.Tau.Sx, pk.Alpha.S, pk.Alpha.Sx, pk.Beta.S, pk.Beta.Sx} {
+		putG1(b, p)
+	}
+	for _, p := range []*bls12.EP2{pk.Tau.SxG2x, pk.Alpha.SxG2x, pk.Beta.SxG2x} {
+		putG2(b, p)
+	}
+	b.Write(c.PartialHash)
+	b.Write(c.NextChallenge)
+
+	var params []byte
+	if c.Name != "" {
+		if len(c.Name) > 255 {
+			return errors.New("the contribution name is too long")
+		}
+		params = append(params, 1, byte(len(c.Name)))
+		params = append(params, c.Name...)
+	}
+	if c.Beacon {
+		if c.BeaconIterations > 255 || len(c.BeaconHash) > 255 {
+			return errors.New("invalid beacon parameters")
+		}
+		putUint32(b, 1)
+		params = append(params, 2, byte(c.BeaconIterations))
+		params = append(params, 3, byte(len(c.BeaconHash)))
+		params = append(params, c.BeaconHash...)
+	} else {
+		putUint32(b, 0)
+	}
+	putUint32(b, uint32(len(params)))
+	b.Write(params)
+	return nil
+}
+
+func putG1(b *bytes.Buffer, p *bls12.EP) {
+	out := make([]byte, bls12.G1UncompressedSize)
+	g1ToPtau(out, p.EncodeUncompressed())
+	b.Write(out)
+}
+
+func putG2(b *bytes.Buffer, p *bls12.EP2) {
+	out := make([]byte, bls12.G2UncompressedSize)
+	g2ToPtau(out, p.EncodeUncompressed())
+	b.Write(out)
+}
+
+func readContributions(r io.Reader, checkSubgroup bool) ([]*Contribution, error) {
+	n, err := readUint32(r)
+	if err != nil {
+		return nil, err
+	}
+	contributions := []*Contribution{}
+	for i := uint32(0); i < n; i++ {
+		c, err := readContribution(r, checkSubgroup)
+		if err != nil {
+			return nil, fmt.Errorf("invalid contribution %d: %v", i, err)
+		}
+		contributions = append(contributions, c)
+	}
+	return contributions, nil
+}
+
+func readContribution(r io.Reader, checkSubgroup bool) (*Contribution, error) {
+	// Convert the points, and read them like those of a challenge file.
+	pr := &pointReader{r: r, noHeaders: true, sections: []pointSection{
+		{0, 1, false}, {0, 1, true}, {0, 2, false}, {0, 1, true},
+		{0, 6, false}, {0, 3, true},
+	}}
+	c := &Contribution{}
+	var err error
+	if c.TauG1, err = powersoftau.ReadG1(pr, checkSubgroup); err != nil {
+		return nil, err
+	}
+	if c.TauG2, err = powersoftau.ReadG2(pr, checkSubgroup); err != nil {
+		return nil, err
+	}
+	if c.AlphaG1, err = powersoftau.ReadG1(pr, checkSubgroup); err != nil {
+		return nil, err
+	}
+	if c.BetaG1, err = powersoftau.ReadG1(pr, checkSubgroup); err != nil {
+		return nil, err
+	}
+	if c.BetaG2, err = powersoftau.ReadG2(pr, checkSubgroup); err != nil {
+		return nil, err
+	}
+	if c.PublicKey, err = powersoftau.ReadPublicKey(pr, checkSubgroup); err != nil {
+		return nil, err
+	}
+
+	c.PartialHash = make([]byte, partialHashSize)
+	if _, err := io.ReadFull(r, c.PartialHash); err != nil {
+		return nil, err
+	}
+	c.NextChallenge = make([]byte, blake2b.Size)
+	if _, err := io.ReadFull(r, c.NextChallenge); err != nil {
+		return nil, err
+	}
+	typ, err := readUint32(r)
+	if err != nil {
+		return nil, err
+	}
+	if typ > 1 {
+		return nil, fmt.Errorf("unknown contribution type %d", typ)
+	}
+	c.Beacon = typ == 1
+	size, err := readUint32(r)
+	if err != nil {
+		return nil, err
+	}
+	params := make([]byte, size)
+	if _, err := io.ReadFull(r, params); err != nil {
+		return nil, err
+	}
+	for len(params) > 0 {
+		key := params[0]
+		if len(params) < 2 {
+			return nil, errors.New("truncated parameters")
+		}
+		switch key {
+		case 1, 3:
+			l := int(params[1])
+			if len(params) < 2+l {
+				return nil, errors.New("truncated parameters")
+			}
+			if key == 1 {
+				c.Name = string(params[2 : 2+l])
+			} else {
+				c.BeaconHash = append([]byte{}, params[2:2+l]...)
+			}
+			params = params[2+l:]
+		case 2:
+			c.BeaconIterations = uint(params[1])
+			params = params[2:]
+		default:
+			return nil, fmt.Errorf("unknown parameter %d", key)
+		}
+	}
+	return c, nil
+}
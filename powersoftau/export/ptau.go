@@ -0,0 +1,493 @@
+// Package export converts accumulators between the ebfull/pairing
+// encoding of the Powers of Tau ceremony and the .ptau format of snarkjs.
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+
+	"github.com/FiloSottile/powersoftau/bls12"
+	"github.com/FiloSottile/powersoftau/powersoftau"
+)
+
+/*
+
+A .ptau file is "ptau", a version and a number of sections, each a type, a
+size and its content. All integers are little-endian, 32 bits except the
+section sizes, which are 64 bits. The sections written here are:
+
+	1. The header: the size of a field element (48), the field modulus q,
+	   the power and the power of the ceremony (the same, as files are
+	   never truncated here)
+	2. TauG1
+	3. TauG2
+	4. AlphaTau
+	5. BetaTau
+	6. BetaG2
+	7. The contributions, see contribution.go
+
+Points are uncompressed and the point at infinity is all zeroes. Field
+elements are in Montgomery form, x * 2^384 mod q, and little-endian, and
+G2 coordinates are c0 then c1, the reverse of the ebfull/pairing order.
+
+*/
+
+const (
+	sectionHeader        = 1
+	sectionTauG1         = 2
+	sectionTauG2         = 3
+	sectionAlphaTau      = 4
+	sectionBetaTau       = 5
+	sectionBetaG2        = 6
+	sectionContributions = 7
+)
+
+const (
+	ptauMagic   = "ptau"
+	ptauVersion = 1
+)
+
+// infinityFlag is the ebfull/pairing serialization flag for the point at
+// infinity, in the first byte.
+const infinityFlag = 1 << 6
+
+// q is the BLS12-381 base field modulus.
+var q = new(big.Int).SetBytes(bls12.FieldModulus())
+
+var (
+	montgomeryR    = new(big.Int).Mod(new(big.Int).Lsh(big.NewInt(1), 8*bls12.FqElementSize), q)
+	montgomeryRInv = new(big.Int).ModInverse(montgomeryR, q)
+)
+
+// A Ptau is the content of a .ptau file for BLS12-381.
+type Ptau struct {
+	Parameters    *powersoftau.Parameters
+	Accumulator   *powersoftau.Accumulator
+	Contributions []*Contribution
+}
+
+// WritePtau writes p to w in the .ptau format.
+func WritePtau(w io.Writer, p *Ptau) error {
+	params, a := p.Parameters, p.Accumulator
+	if len(a.TauG1) != params.TauPowersG1 || len(a.TauG2) != params.TauPowers ||
+		len(a.AlphaTau) != params.TauPowers || len(a.BetaTau) != params.TauPowers {
+		return fmt.Errorf("the accumulator does not have 2^%d powers", params.Power)
+	}
+
+	contributions := &bytes.Buffer{}
+	putUint32(contributions, uint32(len(p.Contributions)))
+	for _, c := range p.Contributions {
+		if err := c.writeTo(contributions); err != nil {
+			return err
+		}
+	}
+
+	header := &bytes.Buffer{}
+	putUint32(header, bls12.FqElementSize)
+	header.Write(littleEndian(q))
+	putUint32(header, uint32(params.Power))
+	putUint32(header, uint32(params.Power))
+
+	file := &bytes.Buffer{}
+	file.WriteString(ptauMagic)
+	putUint32(file, ptauVersion)
+	putUint32(file, 7)
+	putSectionHeader(file, sectionHeader, int64(header.Len()))
+	file.Write(header.Bytes())
+	if _, err := w.Write(file.Bytes()); err != nil {
+		return err
+	}
+
+	if err := writeG1Section(w, sectionTauG1, a.TauG1); err != nil {
+		return err
+	}
+	if err := writeG2Section(w, sectionTauG2, a.TauG2); err != nil {
+		return err
+	}
+	if err := writeG1Section(w, sectionAlphaTau, a.AlphaTau); err != nil {
+		return err
+	}
+	if err := writeG1Section(w, sectionBetaTau, a.BetaTau); err != nil {
+		return err
+	}
+	if err := writeG2Section(w, sectionBetaG2, []*bls12.EP2{a.BetaG2}); err != nil {
+		return err
+	}
+
+	file.Reset()
+	putSectionHeader(file, sectionContributions, int64(contributions.Len()))
+	file.Write(contributions.Bytes())
+	_, err := w.Write(file.Bytes())
+	return err
+}
+
+func writeG1Section(w io.Writer, section uint32, s []*bls12.EP) error {
+	buf := &bytes.Buffer{}
+	putSectionHeader(buf, section, int64(len(s))*bls12.G1UncompressedSize)
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	out := make([]byte, bls12.G1UncompressedSize)
+	for i := 0; i < len(s); i += 1 << 10 {
+		chunk := s[i:powersoftau.ChunkEnd(i, len(s))]
+		bls12.NormalizeBatchG1(chunk)
+		buf.Reset()
+		for _, p := range chunk {
+			g1ToPtau(out, p.EncodeUncompressed())
+			buf.Write(out)
+		}
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeG2Section(w io.Writer, section uint32, s []*bls12.EP2) error {
+	buf := &bytes.Buffer{}
+	putSectionHeader(buf, section, int64(len(s))*bls12.G2UncompressedSize)
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	out := make([]byte, bls12.G2UncompressedSize)
+	for i := 0; i < len(s); i += 1 << 10 {
+		chunk := s[i:powersoftau.ChunkEnd(i, len(s))]
+		bls12.NormalizeBatchG2(chunk)
+		buf.Reset()
+		for _, p := range chunk {
+			g2ToPtau(out, p.EncodeUncompressed())
+			buf.Write(out)
+		}
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadPtau reads a .ptau file for BLS12-381. The header and the TauG1 to
+// BetaG2 sections must come first and in order, like snarkjs writes them.
+// Sections other than the contributions, like the Lagrange basis added by
+// "snarkjs powersoftau prepare phase2", are skipped. All points are checked
+// to be on the curve, and if checkSubgroup is true also to be in the prime
+// order subgroup.
+func ReadPtau(r io.Reader, checkSubgroup bool) (*Ptau, error) {
+	magic := make([]byte, len(ptauMagic))
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return nil, err
+	}
+	if string(magic) != ptauMagic {
+		return nil, errors.New("not a .ptau file")
+	}
+	version, err := readUint32(r)
+	if err != nil {
+		return nil, err
+	}
+	if version != ptauVersion {
+		return nil, fmt.Errorf("unsupported .ptau version %d", version)
+	}
+	sections, err := readUint32(r)
+	if err != nil {
+		return nil, err
+	}
+	if sections < 6 {
+		return nil, errors.New("missing .ptau sections")
+	}
+
+	section, size, err := readSectionHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	if section != sectionHeader {
+		return nil, errors.New("the .ptau file does not start with the header")
+	}
+	params, err := readHeader(&io.LimitedReader{R: r, N: size}, size)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Ptau{Parameters: params}
+	pr := &pointReader{r: r, sections: []pointSection{
+		{sectionTauG1, params.TauPowersG1, false},
+		{sectionTauG2, params.TauPowers, true},
+		{sectionAlphaTau, params.TauPowers, false},
+		{sectionBetaTau, params.TauPowers, false},
+		{sectionBetaG2, 1, true},
+	}}
+	p.Accumulator, err = powersoftau.ReadAccumulator(pr, params, false, checkSubgroup)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := uint32(6); i < sections; i++ {
+		section, size, err := readSectionHeader(r)
+		if err != nil {
+			return nil, err
+		}
+		lr := &io.LimitedReader{R: r, N: size}
+		if section == sectionContributions {
+			if p.Contributions != nil {
+				return nil, errors.New("duplicate .ptau contributions section")
+			}
+			p.Contributions, err = readContributions(lr, checkSubgroup)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if _, err := io.Copy(ioutil.Discard, lr); err != nil {
+			return nil, err
+		}
+		if lr.N != 0 {
+			return nil, io.ErrUnexpectedEOF
+		}
+	}
+	return p, nil
+}
+
+func readHeader(r io.Reader, size int64) (*powersoftau.Parameters, error) {
+	if size != 4+bls12.FqElementSize+4+4 {
+		return nil, errors.New("the .ptau file is not for BLS12-381")
+	}
+	n8, err := readUint32(r)
+	if err != nil {
+		return nil, err
+	}
+	modulus := make([]byte, bls12.FqElementSize)
+	if _, err := io.ReadFull(r, modulus); err != nil {
+		return nil, err
+	}
+	if n8 != bls12.FqElementSize || !bytes.Equal(modulus, littleEndian(q)) {
+		return nil, errors.New("the .ptau file is not for BLS12-381")
+	}
+	power, err := readUint32(r)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := readUint32(r); err != nil { // ceremony power
+		return nil, err
+	}
+	return powersoftau.NewParameters(uint(power))
+}
+
+type pointSection struct {
+	section uint32
+	n       int
+	g2      bool
+}
+
+// pointReader reads the point sections of a .ptau file, and returns them
+// re-encoded like in a challenge file, for ReadAccumulator. If noHeaders is
+// true, the sections are just consecutive points, like in a contribution.
+type pointReader struct {
+	r         io.Reader
+	sections  []pointSection
+	noHeaders bool
+	left      int
+	buf       []byte
+}
+
+func (pr *pointReader) Read(b []byte) (int, error) {
+	if len(pr.buf) == 0 {
+		if err := pr.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(b, pr.buf)
+	pr.buf = pr.buf[n:]
+	return n, nil
+}
+
+// next reads one point into pr.buf, moving to the next section if needed.
+func (pr *pointReader) next() error {
+	for pr.left == 0 {
+		if len(pr.sections) == 0 {
+			return io.EOF
+		}
+		s := pr.sections[0]
+		if pr.noHeaders {
+			pr.left = s.n
+			break
+		}
+		section, size, err := readSectionHeader(pr.r)
+		if err != nil {
+			return err
+		}
+		pointSize := int64(bls12.G1UncompressedSize)
+		if s.g2 {
+			pointSize = bls12.G2UncompressedSize
+		}
+		if section != s.section || size != int64(s.n)*pointSize {
+			return fmt.Errorf("unexpected .ptau section %d of size %d", section, size)
+		}
+		pr.left = s.n
+	}
+	s := pr.sections[0]
+	var in []byte
+	if s.g2 {
+		in = make([]byte, bls12.G2UncompressedSize)
+	} else {
+		in = make([]byte, bls12.G1UncompressedSize)
+	}
+	if _, err := io.ReadFull(pr.r, in); err != nil {
+		return err
+	}
+	pr.buf = make([]byte, len(in))
+	var err error
+	if s.g2 {
+		err = g2FromPtau(pr.buf, in)
+	} else {
+		err = g1FromPtau(pr.buf, in)
+	}
+	if err != nil {
+		return err
+	}
+	if pr.left--; pr.left == 0 {
+		pr.sections = pr.sections[1:]
+	}
+	return nil
+}
+
+// g1ToPtau converts an uncompressed ebfull/pairing G1 encoding to out.
+func g1ToPtau(out, in []byte) {
+	const n = bls12.FqElementSize
+	if in[0]&infinityFlag != 0 {
+		zero(out)
+		return
+	}
+	toMontgomery(out[:n], in[:n])
+	toMontgomery(out[n:], in[n:])
+}
+
+// g2ToPtau converts an uncompressed ebfull/pairing G2 encoding to out.
+func g2ToPtau(out, in []byte) {
+	const n = bls12.FqElementSize
+	if in[0]&infinityFlag != 0 {
+		zero(out)
+		return
+	}
+	toMontgomery(out[:n], in[n:2*n])
+	toMontgomery(out[n:2*n], in[:n])
+	toMontgomery(out[2*n:3*n], in[3*n:])
+	toMontgomery(out[3*n:], in[2*n:3*n])
+}
+
+// g1FromPtau converts a .ptau G1 point to the uncompressed ebfull/pairing
+// encoding. It doesn't check that the point is on the curve.
+func g1FromPtau(out, in []byte) error {
+	const n = bls12.FqElementSize
+	if isZero(in) {
+		zero(out)
+		out[0] = infinityFlag
+		return nil
+	}
+	if err := fromMontgomery(out[:n], in[:n]); err != nil {
+		return err
+	}
+	return fromMontgomery(out[n:], in[n:])
+}
+
+// g2FromPtau is like g1FromPtau, for G2 points.
+func g2FromPtau(out, in []byte) error {
+	const n = bls12.FqElementSize
+	if isZero(in) {
+		zero(out)
+		out[0] = infinityFlag
+		return nil
+	}
+	for _, c := range [][2]int{{n, 0}, {0, n}, {3 * n, 2 * n}, {2 * n, 3 * n}} {
+		if err := fromMontgomery(out[c[0]:c[0]+n], in[c[1]:c[1]+n]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// toMontgomery writes the big-endian field element in to out, as x * R
+// mod q in little-endian.
+func toMontgomery(out, in []byte) {
+	x := new(big.Int).SetBytes(in)
+	x.Mul(x, montgomeryR).Mod(x, q)
+	copy(out, littleEndian(x))
+}
+
+// fromMontgomery reverses toMontgomery.
+func fromMontgomery(out, in []byte) error {
+	x := new(big.Int).SetBytes(reverse(in))
+	if x.Cmp(q) >= 0 {
+		return errors.New("invalid .ptau field element")
+	}
+	x.Mul(x, montgomeryRInv).Mod(x, q)
+	b := x.Bytes()
+	zero(out[:len(out)-len(b)])
+	copy(out[len(out)-len(b):], b)
+	return nil
+}
+
+// littleEndian returns x as a little-endian field element.
+func littleEndian(x *big.Int) []byte {
+	b := x.Bytes()
+	out := make([]byte, bls12.FqElementSize)
+	copy(out[len(out)-len(b):], b)
+	return reverse(out)
+}
+
+func reverse(b []byte) []byte {
+	out := make([]byte, len(b))
+	for i := range b {
+		out[len(b)-1-i] = b[i]
+	}
+	return out
+}
+
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+func isZero(b []byte) bool {
+	for _, x := range b {
+		if x != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func putUint32(b *bytes.Buffer, v uint32) {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], v)
+	b.Write(buf[:])
+}
+
+func putSectionHeader(b *bytes.Buffer, section uint32, size int64) {
+	putUint32(b, section)
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], uint64(size))
+	b.Write(buf[:])
+}
+
+func readUint32(r io.Reader) (uint32, error) {
+	var buf [4]byte
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(buf[:]), nil
+}
+
+func readSectionHeader(r io.Reader) (section uint32, size int64, err error) {
+	var buf [12]byte
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		return 0, 0, err
+	}
+	size = int64(binary.LittleEndian.Uint64(buf[4:]))
+	if size < 0 {
+		return 0, 0, errors.New("invalid .ptau section size")
+	}
+	return binary.LittleEndian.Uint32(buf[:4]), size, nil
+}
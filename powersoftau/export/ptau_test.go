@@ -0,0 +1,181 @@
+package export
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/FiloSottile/powersoftau/bls12"
+	"github.com/FiloSottile/powersoftau/powersoftau"
+	"golang.org/x/crypto/blake2b"
+)
+
+func TestBlake2bPartial(t *testing.T) {
+	msg := make([]byte, 1000)
+	rand.Read(msg)
+	for _, n := range []int{0, 1, 64, 127, 128, 129, 256, 300, 1000} {
+		for _, split := range []int{0, n / 3, n} {
+			h := newBlake2b()
+			h.Write(msg[:split])
+			r, err := resumeBlake2b(h.MarshalPartial())
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Write(msg[split:n])
+			if expected := blake2b.Sum512(msg[:n]); !bytes.Equal(r.Sum(), expected[:]) {
+				t.Errorf("wrong hash of %d bytes resumed at %d", n, split)
+			}
+		}
+	}
+}
+
+func TestBlake2bPartialLayout(t *testing.T) {
+	// After 300 bytes, two blocks are compressed and 44 bytes are pending.
+	h := newBlake2b()
+	h.Write(make([]byte, 300))
+	partial := h.MarshalPartial()
+	expected := []byte{
+		0, 1, 0, 0, 0, 0, 0, 0, // t[0] = 256
+		0, 0, 0, 0, 0, 0, 0, 0, // t[1]
+		44, 0, 0, 0, // c
+		64, 0, 0, 0, // outlen
+	}
+	if !bytes.Equal(partial[192:], expected) {
+		t.Errorf("got counters %x, expected %x", partial[192:], expected)
+	}
+
+	partial[212] = 32
+	if _, err := resumeBlake2b(partial); err == nil {
+		t.Error("resumed a BLAKE2b-256 state")
+	}
+}
+
+func TestPtauRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	params, _ := powersoftau.NewParameters(3)
+	challengeFile := filepath.Join(dir, "challenge")
+	responseFile := filepath.Join(dir, "response")
+	nextFile := filepath.Join(dir, "next")
+	if err := powersoftau.WriteNewChallenge(challengeFile, params); err != nil {
+		t.Fatal(err)
+	}
+	c, err := powersoftau.ReadChallenge(challengeFile, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Compute(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := powersoftau.WriteResponse(responseFile, c); err != nil {
+		t.Fatal(err)
+	}
+	if err := powersoftau.WriteNextChallenge(nextFile, c); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := powersoftau.ReadResponse(responseFile, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next, err := powersoftau.ReadChallenge(nextFile, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contribution, err := NewContribution(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	contribution.Name = "test"
+	if !bytes.Equal(contribution.NextChallenge, next.ChallengeHash) {
+		t.Error("wrong next challenge hash")
+	}
+
+	buf := &bytes.Buffer{}
+	if err := WritePtau(buf, &Ptau{
+		Parameters:    params,
+		Accumulator:   resp.Accumulator,
+		Contributions: []*Contribution{contribution},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// Add an unknown section, like the Lagrange basis of prepared files.
+	b := buf.Bytes()
+	b[8]++
+	b = append(b, 12, 0, 0, 0, 3, 0, 0, 0, 0, 0, 0, 0, 1, 2, 3)
+
+	p, err := ReadPtau(bytes.NewReader(b), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Parameters.Power != params.Power {
+		t.Errorf("wrong power %d", p.Parameters.Power)
+	}
+	got, expected := &bytes.Buffer{}, &bytes.Buffer{}
+	p.Accumulator.WriteTo(got, false)
+	resp.Accumulator.WriteTo(expected, false)
+	if !bytes.Equal(got.Bytes(), expected.Bytes()) {
+		t.Error("the accumulator changed in the round-trip")
+	}
+
+	if len(p.Contributions) != 1 {
+		t.Fatalf("got %d contributions", len(p.Contributions))
+	}
+	c1 := p.Contributions[0]
+	if c1.Name != "test" || c1.Beacon || !bytes.Equal(c1.NextChallenge, contribution.NextChallenge) {
+		t.Errorf("wrong contribution: %+v", c1)
+	}
+	if !bytes.Equal(c1.TauG1.EncodeUncompressed(), resp.Accumulator.TauG1[1].EncodeUncompressed()) ||
+		!bytes.Equal(c1.BetaG2.EncodeUncompressed(), resp.Accumulator.BetaG2.EncodeUncompressed()) {
+		t.Error("wrong contribution points")
+	}
+	responseHash, err := c1.ResponseHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(responseHash, resp.ResponseHash) {
+		t.Error("wrong response hash")
+	}
+}
+
+func TestPtauPoints(t *testing.T) {
+	inf := (&bls12.EP{}).SetZero()
+	out := make([]byte, bls12.G1UncompressedSize)
+	g1ToPtau(out, inf.EncodeUncompressed())
+	if !isZero(out) {
+		t.Error("the point at infinity is not all zeroes")
+	}
+	back := make([]byte, bls12.G1UncompressedSize)
+	if err := g1FromPtau(back, out); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(back, inf.EncodeUncompressed()) {
+		t.Error("wrong point at infinity")
+	}
+
+	g2 := bls12.NewEP2().SetOne()
+	defer g2.Close()
+	out2 := make([]byte, bls12.G2UncompressedSize)
+	g2ToPtau(out2, g2.EncodeUncompressed())
+	back2 := make([]byte, bls12.G2UncompressedSize)
+	if err := g2FromPtau(back2, out2); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(back2, g2.EncodeUncompressed()) {
+		t.Error("wrong G2 generator")
+	}
+
+	// Field elements must be reduced.
+	bad := make([]byte, bls12.G1UncompressedSize)
+	copy(bad, littleEndian(q))
+	bad[0]++
+	if err := g1FromPtau(back, bad); err == nil {
+		t.Error("accepted an element larger than q")
+	}
+}
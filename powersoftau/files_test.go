@@ -0,0 +1,135 @@
+package powersoftau
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// checkNoFiles checks that dir contains only the named files.
+func checkNoFiles(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := make(map[string]bool)
+	for _, name := range names {
+		expected[name] = true
+	}
+	for _, fi := range fis {
+		if !expected[fi.Name()] {
+			t.Errorf("unexpected file %s", fi.Name())
+		}
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir, err := ioutil.TempDir("", "powersoftau")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "file")
+
+	if err := ioutil.WriteFile(filename, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err = WriteFileAtomic(context.Background(), filename, func(w io.Writer) error {
+		w.Write([]byte("partial"))
+		return errors.New("failure")
+	})
+	if err == nil || err.Error() != "failure" {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if b, _ := ioutil.ReadFile(filename); string(b) != "old" {
+		t.Errorf("file was overwritten after a failure: %q", b)
+	}
+	checkNoFiles(t, dir, "file")
+
+	if err := WriteFileAtomic(context.Background(), filename, func(w io.Writer) error {
+		_, err := w.Write([]byte("new"))
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := ioutil.ReadFile(filename); string(b) != "new" {
+		t.Errorf("file was not written: %q", b)
+	}
+	checkNoFiles(t, dir, "file")
+}
+
+func TestCancel(t *testing.T) {
+	dir, err := ioutil.TempDir("", "powersoftau")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	challengeFile := filepath.Join(dir, "challenge")
+	writeTestChallenge(t, challengeFile, testParams)
+
+	// cancelHalfway returns a context and a ProgressFunc that cancels it
+	// halfway through.
+	cancelHalfway := func() (context.Context, ProgressFunc) {
+		ctx, cancel := context.WithCancel(context.Background())
+		return ctx, func(done, total int64) {
+			if done >= total/2 {
+				cancel()
+			}
+		}
+	}
+
+	ctx, progress := cancelHalfway()
+	if _, err := ReadChallengeContext(ctx, challengeFile, nil, false, progress); err != context.Canceled {
+		t.Errorf("ReadChallengeContext: unexpected error: %v", err)
+	}
+
+	c, err := ReadChallenge(challengeFile, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, c.Progress = cancelHalfway()
+	if err := c.ComputeWithContext(ctx, 2, fixedKeypair()); err != context.Canceled {
+		t.Errorf("ComputeWithContext: unexpected error: %v", err)
+	}
+	if c.PublicKey != nil {
+		t.Error("ComputeWithContext: PublicKey set after cancellation")
+	}
+
+	if err := c.Compute(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteResponse(filepath.Join(dir, "response"), c); err != nil {
+		t.Fatal(err)
+	}
+	ctx, progress = cancelHalfway()
+	if _, err := ReadResponseContext(ctx, filepath.Join(dir, "response"), nil, false, progress); err != context.Canceled {
+		t.Errorf("ReadResponseContext: unexpected error: %v", err)
+	}
+	if err := os.Remove(filepath.Join(dir, "response")); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, c.Progress = cancelHalfway()
+	if err := WriteResponseContext(ctx, filepath.Join(dir, "response"), c); err != context.Canceled {
+		t.Errorf("WriteResponseContext: unexpected error: %v", err)
+	}
+	ctx, c.Progress = cancelHalfway()
+	if err := WriteNextChallengeContext(ctx, filepath.Join(dir, "next"), c); err != context.Canceled {
+		t.Errorf("WriteNextChallengeContext: unexpected error: %v", err)
+	}
+
+	ctx, progress = cancelHalfway()
+	_, err = ComputeStreamContext(ctx, challengeFile, filepath.Join(dir, "response"), &StreamOptions{
+		NextFile: filepath.Join(dir, "next"), NewKeypair: fixedKeypair(), Progress: progress,
+	})
+	if err != context.Canceled {
+		t.Errorf("ComputeStreamContext: unexpected error: %v", err)
+	}
+
+	checkNoFiles(t, dir, "challenge")
+}
@@ -0,0 +1,95 @@
+package powersoftau
+
+import (
+	"bytes"
+	"context"
+	"io"
+
+	"github.com/FiloSottile/powersoftau/bls12"
+	"golang.org/x/crypto/blake2b"
+)
+
+// NewAccumulator returns the initial accumulator of a ceremony, where all
+// the secrets are one and so all points are the generators.
+func NewAccumulator(params *Parameters) *Accumulator {
+	a := &Accumulator{
+		TauG1:    make([]*bls12.EP, params.TauPowersG1),
+		TauG2:    make([]*bls12.EP2, params.TauPowers),
+		AlphaTau: make([]*bls12.EP, params.TauPowers),
+		BetaTau:  make([]*bls12.EP, params.TauPowers),
+		BetaG2:   bls12.NewEP2().SetOne(),
+	}
+	for i := range a.TauG1 {
+		a.TauG1[i] = (&bls12.EP{}).SetOne()
+	}
+	for i := range a.TauG2 {
+		a.TauG2[i] = bls12.NewEP2().SetOne()
+		a.AlphaTau[i] = (&bls12.EP{}).SetOne()
+		a.BetaTau[i] = (&bls12.EP{}).SetOne()
+	}
+	return a
+}
+
+// NewChallenge returns the first challenge of a ceremony. Like in the
+// original implementation, the hash of the previous response is the
+// BLAKE2b hash of the empty string.
+func NewChallenge(params *Parameters) *Challenge {
+	h := blake2b.Sum512(nil)
+	return &Challenge{
+		ResponseHash: h[:],
+		Parameters:   params,
+		Accumulator:  NewAccumulator(params),
+	}
+}
+
+// WriteNewChallenge writes the first challenge of a ceremony to filename,
+// like WriteNextChallenge(filename, NewChallenge(params)), but without
+// holding the accumulator in memory.
+func WriteNewChallenge(filename string, params *Parameters) error {
+	return WriteFileAtomic(context.Background(), filename, func(w io.Writer) error {
+		return writeNewChallenge(w, params)
+	})
+}
+
+// NewChallengeHash returns the BLAKE2b hash of the first challenge of a
+// ceremony, as written by WriteNewChallenge.
+func NewChallengeHash(params *Parameters) []byte {
+	h, _ := blake2b.New512(nil)
+	writeNewChallenge(h, params)
+	return h.Sum(nil)
+}
+
+func writeNewChallenge(w io.Writer, params *Parameters) error {
+	h := blake2b.Sum512(nil)
+	if _, err := w.Write(h[:]); err != nil {
+		return err
+	}
+
+	g2 := bls12.NewEP2().SetOne()
+	defer g2.Close()
+	g1 := (&bls12.EP{}).SetOne().EncodeUncompressed()
+	g2Enc := g2.EncodeUncompressed()
+	for _, s := range []struct {
+		point []byte
+		n     int
+	}{
+		{g1, params.TauPowersG1},  // TauG1
+		{g2Enc, params.TauPowers}, // TauG2
+		{g1, params.TauPowers},    // AlphaTau
+		{g1, params.TauPowers},    // BetaTau
+		{g2Enc, 1},                // BetaG2
+	} {
+		chunk := bytes.Repeat(s.point, 1<<10)
+		for n := s.n; n > 0; {
+			k := n
+			if k > 1<<10 {
+				k = 1 << 10
+			}
+			if _, err := w.Write(chunk[:k*len(s.point)]); err != nil {
+				return err
+			}
+			n -= k
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package powersoftau
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+func TestNewChallenge(t *testing.T) {
+	dir, err := ioutil.TempDir("", "powersoftau")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := WriteNewChallenge(filepath.Join(dir, "challenge1"), testParams); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteNextChallenge(filepath.Join(dir, "challenge2"), NewChallenge(testParams)); err != nil {
+		t.Fatal(err)
+	}
+	a, err := ioutil.ReadFile(filepath.Join(dir, "challenge1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(dir, "challenge2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(a, b) {
+		t.Error("WriteNewChallenge and NewChallenge produced different files")
+	}
+	if h := blake2b.Sum512(a); !bytes.Equal(NewChallengeHash(testParams), h[:]) {
+		t.Error("NewChallengeHash is not the hash of the new challenge")
+	}
+
+	// Run a whole two-contribution ceremony from the new challenge.
+	challengeFile := filepath.Join(dir, "challenge1")
+	for i, name := range []string{"a", "b"} {
+		c, err := ComputeStream(challengeFile, filepath.Join(dir, "response"+name), &StreamOptions{
+			NextFile: filepath.Join(dir, "next"+name), Processes: 2,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ch, err := ReadChallenge(challengeFile, nil, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := ReadResponse(filepath.Join(dir, "response"+name), nil, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ch.Verify(r, 2); err != nil {
+			t.Errorf("contribution %d failed verification: %v", i, err)
+		}
+		if !bytes.Equal(c.ResponseHash, r.ResponseHash) {
+			t.Errorf("contribution %d: wrong response hash", i)
+		}
+		challengeFile = filepath.Join(dir, "next"+name)
+	}
+}
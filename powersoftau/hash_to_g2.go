@@ -57,12 +57,7 @@ Here is a reversed spec:
 */
 
 func HashToG2(digest []byte) *bls12.EP2 {
-	var key [32]byte
-	for i := 0; i < 32; i += 4 {
-		k := binary.LittleEndian.Uint32(digest[i:])
-		binary.BigEndian.PutUint32(key[i:], k)
-	}
-	rng := chacha20.NewRng(&key)
+	rng := newRustRng(digest)
 
 	p := bls12.NewEP2()
 	for {
@@ -94,9 +89,21 @@ func HashToG2(digest []byte) *bls12.EP2 {
 	}
 }
 
-var fqModulus = [48]byte{0x1a, 0x01, 0x11, 0xea, 0x39, 0x7f, 0xe6, 0x9a, 0x4b, 0x1b, 0xa7, 0xb6, 0x43, 0x4b, 0xac, 0xd7, 0x64, 0x77, 0x4b, 0x84, 0xf3, 0x85, 0x12, 0xbf, 0x67, 0x30, 0xd2, 0xa0, 0xf6, 0xb0, 0xf6, 0x24, 0x1e, 0xab, 0xff, 0xfe, 0xb1, 0x53, 0xff, 0xff, 0xb9, 0xfe, 0xff, 0xff, 0xff, 0xff, 0xaa, 0xab}
+// newRustRng returns the ChaCha20 RNG that the Rust code seeds with the
+// first 32 bytes of seed read as big-endian uint32 [read_u32::<BigEndian>]
+// [ChaChaRng::from_seed]. Those are then used as the little-endian words
+// of the ChaCha20 key, so we reverse the byte order of each word.
+func newRustRng(seed []byte) *chacha20.Rng {
+	var key [32]byte
+	for i := 0; i < 32; i += 4 {
+		k := binary.LittleEndian.Uint32(seed[i:])
+		binary.BigEndian.PutUint32(key[i:], k)
+	}
+	return chacha20.NewRng(&key)
+}
 
 func extractFieldElement(rng *chacha20.Rng) [48]byte {
+	modulus := bls12.FieldModulus()
 	for {
 		var res [48]byte
 		for i := 48 - 8; i >= 0; i -= 8 {
@@ -104,7 +111,7 @@ func extractFieldElement(rng *chacha20.Rng) [48]byte {
 			binary.BigEndian.PutUint32(res[i+4:], rng.ReadUint32())
 		}
 		res[0] &= 0xff >> 3
-		if bytes.Compare(res[:], fqModulus[:]) >= 0 {
+		if bytes.Compare(res[:], modulus) >= 0 {
 			continue
 		}
 		bls12.FqMontgomeryReduce(res[:])
@@ -0,0 +1,233 @@
+package powersoftau
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"math/bits"
+	"os"
+
+	"github.com/FiloSottile/powersoftau/bls12"
+)
+
+/*
+
+The Lagrange basis over a domain of the n-th roots of unity 1, ω, ..., ω^(n-1)
+is the polynomials L_i which are 1 at ω^i and 0 at the other roots. As
+
+	L_i(x) = 1/n Σ_j ω^(-ij) x^j
+
+the points L_i(τ) * G are the inverse Fourier transform of the first n powers
+of τ in the accumulator, which is computed with a radix-2 FFT where the
+multiplications by the roots are scalar multiplications of points.
+
+The scalar field of BLS12-381 has 2^32 roots of unity, generated by
+7^((r-1) >> 32), as 7 generates the whole multiplicative group.
+
+*/
+
+// LagrangeG1 returns L_i(τ) * G1 for i < domainSize, where L_i are the
+// Lagrange polynomials over the domainSize-th roots of unity. domainSize
+// must be a power of two no larger than the number of powers. The FFT
+// runs on processes goroutines.
+func (a *Accumulator) LagrangeG1(domainSize, processes int) ([]*bls12.EP, error) {
+	return lagrangeG1(a.TauG1, domainSize, len(a.TauG2), processes)
+}
+
+// LagrangeAlphaG1 is like LagrangeG1, but returns α * L_i(τ) * G1.
+func (a *Accumulator) LagrangeAlphaG1(domainSize, processes int) ([]*bls12.EP, error) {
+	return lagrangeG1(a.AlphaTau, domainSize, len(a.TauG2), processes)
+}
+
+// LagrangeBetaG1 is like LagrangeG1, but returns β * L_i(τ) * G1.
+func (a *Accumulator) LagrangeBetaG1(domainSize, processes int) ([]*bls12.EP, error) {
+	return lagrangeG1(a.BetaTau, domainSize, len(a.TauG2), processes)
+}
+
+func lagrangeG1(powers []*bls12.EP, domainSize, maxSize, processes int) ([]*bls12.EP, error) {
+	if err := checkDomain(domainSize, maxSize); err != nil {
+		return nil, err
+	}
+	points := make([]*bls12.EP, domainSize)
+	for i := range points {
+		points[i] = powers[i].Copy()
+	}
+	twiddles, nInv := lagrangeScalars(domainSize)
+	fftG1(points, twiddles, processes)
+	Parallelize(domainSize, processes, func(a, b int) {
+		for i := a; i < b; i++ {
+			points[i].ScalarMult(nInv)
+		}
+		bls12.NormalizeBatchG1(points[a:b])
+	})
+	return points, nil
+}
+
+// LagrangeG2 is like LagrangeG1, but for G2. The points must be freed with
+// Close.
+func (a *Accumulator) LagrangeG2(domainSize, processes int) ([]*bls12.EP2, error) {
+	if err := checkDomain(domainSize, len(a.TauG2)); err != nil {
+		return nil, err
+	}
+	points := make([]*bls12.EP2, domainSize)
+	for i := range points {
+		points[i] = a.TauG2[i].Copy()
+	}
+	twiddles, nInv := lagrangeScalars(domainSize)
+	fftG2(points, twiddles, processes)
+	Parallelize(domainSize, processes, func(a, b int) {
+		for i := a; i < b; i++ {
+			points[i].ScalarMult(nInv)
+		}
+		bls12.NormalizeBatchG2(points[a:b])
+	})
+	return points, nil
+}
+
+func checkDomain(n, powers int) error {
+	if n < 1 || n&(n-1) != 0 {
+		return errors.New("the domain size must be a power of two")
+	}
+	if n > powers {
+		return fmt.Errorf("the domain size must be at most %d", powers)
+	}
+	return nil
+}
+
+// rootOfUnity returns the primitive n-th root of unity ω, for n a power of
+// two up to 2^32.
+func rootOfUnity(n int) *big.Int {
+	r := (&big.Int{}).SetBytes(bls12.ScalarOrder())
+	w := (&big.Int{}).Sub(r, big.NewInt(1))
+	w.Rsh(w, 32)
+	w.Exp(big.NewInt(7), w, r)
+	for k := int64(n); k < 1<<32; k <<= 1 {
+		w.Mul(w, w).Mod(w, r)
+	}
+	return w
+}
+
+// lagrangeScalars returns ω^(-i) for i < n/2, and 1/n.
+func lagrangeScalars(n int) (twiddles [][]byte, nInv []byte) {
+	r := (&big.Int{}).SetBytes(bls12.ScalarOrder())
+	w := rootOfUnity(n)
+	w.ModInverse(w, r)
+
+	k := big.NewInt(1)
+	for i := 0; i < n/2; i++ {
+		twiddles = append(twiddles, k.Bytes())
+		k.Mul(k, w).Mod(k, r)
+	}
+	return twiddles, (&big.Int{}).ModInverse(big.NewInt(int64(n)), r).Bytes()
+}
+
+// bitReverse returns the lowest logN bits of i in reverse order.
+func bitReverse(i, logN int) int {
+	return int(bits.Reverse64(uint64(i)) >> uint(64-logN))
+}
+
+// fftG1 replaces points with Σ_j twiddle^(ij) points[j], where twiddles are
+// the first len(points)/2 powers of a primitive root of unity. Each of the
+// log2(n) passes is spread across workers.
+func fftG1(points []*bls12.EP, twiddles [][]byte, workers int) {
+	n := len(points)
+	logN := bits.Len(uint(n)) - 1
+	for i := range points {
+		if j := bitReverse(i, logN); i < j {
+			points[i], points[j] = points[j], points[i]
+		}
+	}
+	for half := 1; half < n; half <<= 1 {
+		stride := n / (2 * half)
+		Parallelize(n/2, workers, func(a, b int) {
+			for m := a; m < b; m++ {
+				j := m % half
+				k := m/half*2*half + j
+				// (x, y) becomes (x + ωy, x - ωy), as x - ωy = (x + ωy) - 2ωy.
+				y := points[k+half]
+				if j != 0 {
+					y.ScalarMult(twiddles[j*stride])
+				}
+				points[k].Add(y)
+				y.Double().Neg().Add(points[k])
+			}
+		})
+	}
+}
+
+func fftG2(points []*bls12.EP2, twiddles [][]byte, workers int) {
+	n := len(points)
+	logN := bits.Len(uint(n)) - 1
+	for i := range points {
+		if j := bitReverse(i, logN); i < j {
+			points[i], points[j] = points[j], points[i]
+		}
+	}
+	for half := 1; half < n; half <<= 1 {
+		stride := n / (2 * half)
+		Parallelize(n/2, workers, func(a, b int) {
+			for m := a; m < b; m++ {
+				j := m % half
+				k := m/half*2*half + j
+				y := points[k+half]
+				if j != 0 {
+					y.ScalarMult(twiddles[j*stride])
+				}
+				points[k].Add(y)
+				y.Double().Neg().Add(points[k])
+			}
+		})
+	}
+}
+
+// WriteLagrange writes the points returned by LagrangeG1 and LagrangeG2 to
+// filename, the G1 points followed by the G2 points, compressed like in a
+// response or uncompressed like in a challenge. Like WriteResponse, it never
+// leaves a partial file behind.
+func WriteLagrange(filename string, g1 []*bls12.EP, g2 []*bls12.EP2, compressed bool) error {
+	if len(g1) != len(g2) {
+		return errors.New("the G1 and G2 points are over different domains")
+	}
+	return WriteFileAtomic(context.Background(), filename, func(w io.Writer) error {
+		if err := writeG1Slice(w, g1, compressed); err != nil {
+			return err
+		}
+		return writeG2Slice(w, g2, compressed)
+	})
+}
+
+// ReadLagrange reads a file written by WriteLagrange, detecting the domain
+// size from the file size. checkSubgroup works like for ReadChallenge.
+func ReadLagrange(filename string, compressed, checkSubgroup bool) ([]*bls12.EP, []*bls12.EP2, error) {
+	fi, err := os.Stat(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	pointSize := int64(bls12.G1UncompressedSize + bls12.G2UncompressedSize)
+	if compressed {
+		pointSize = bls12.G1CompressedSize + bls12.G2CompressedSize
+	}
+	n := fi.Size() / pointSize
+	if fi.Size()%pointSize != 0 || n == 0 || n&(n-1) != 0 || n > 1<<MaxPower {
+		return nil, nil, errors.New("the Lagrange file has the wrong size")
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	g1, err := readG1Slice(r, "LagrangeG1", int(n), compressed, checkSubgroup)
+	if err != nil {
+		return nil, nil, err
+	}
+	g2, err := readG2Slice(r, "LagrangeG2", int(n), compressed, checkSubgroup)
+	if err != nil {
+		return nil, nil, err
+	}
+	return g1, g2, nil
+}
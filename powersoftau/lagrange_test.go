@@ -0,0 +1,134 @@
+package powersoftau
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/FiloSottile/powersoftau/bls12"
+)
+
+func TestRootOfUnity(t *testing.T) {
+	r := (&big.Int{}).SetBytes(bls12.ScalarOrder())
+	one := big.NewInt(1)
+	for _, n := range []int{2, 16, 1 << 20} {
+		w := rootOfUnity(n)
+		if (&big.Int{}).Exp(w, big.NewInt(int64(n)), r).Cmp(one) != 0 {
+			t.Errorf("ω^%d != 1", n)
+		}
+		if (&big.Int{}).Exp(w, big.NewInt(int64(n/2)), r).Cmp(one) == 0 {
+			t.Errorf("ω is not a primitive %d-th root of unity", n)
+		}
+	}
+}
+
+func TestLagrange(t *testing.T) {
+	dir, err := ioutil.TempDir("", "powersoftau")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	challengeFile := filepath.Join(dir, "challenge")
+	writeTestChallenge(t, challengeFile, testParams)
+	c, err := ReadChallenge(challengeFile, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := c.Accumulator
+	r := (&big.Int{}).SetBytes(bls12.ScalarOrder())
+
+	for _, n := range []int{1, 2, 16} {
+		g1, err := a.LagrangeG1(n, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g2, err := a.LagrangeG2(n, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Σ_i L_i(τ) ω^(ij) = τ^j
+		w := rootOfUnity(n)
+		for j := 0; j < n; j++ {
+			wj := (&big.Int{}).Exp(w, big.NewInt(int64(j)), r)
+			k := big.NewInt(1)
+			sum1, sum2 := (&bls12.EP{}).SetZero(), bls12.NewEP2().SetZero()
+			for i := 0; i < n; i++ {
+				sum1.Add(g1[i].Copy().ScalarMult(k.Bytes()))
+				sum2.Add(g2[i].Copy().ScalarMult(k.Bytes()))
+				k.Mul(k, wj).Mod(k, r)
+			}
+			if !sum1.Equal(a.TauG1[j]) {
+				t.Errorf("n = %d: wrong G1 basis for τ^%d", n, j)
+			}
+			if !sum2.Equal(a.TauG2[j]) {
+				t.Errorf("n = %d: wrong G2 basis for τ^%d", n, j)
+			}
+		}
+	}
+
+	alpha, err := a.LagrangeAlphaG1(4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	beta, err := a.LagrangeBetaG1(4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g1, err := a.LagrangeG1(4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range g1 {
+		// The test accumulator has α = 7 and β = 11.
+		if !alpha[i].Equal(g1[i].Copy().ScalarMult([]byte{7})) || !beta[i].Equal(g1[i].Copy().ScalarMult([]byte{11})) {
+			t.Errorf("wrong α or β basis at %d", i)
+		}
+	}
+
+	if _, err := a.LagrangeG1(3, 2); err == nil {
+		t.Error("accepted a domain size that is not a power of two")
+	}
+	if _, err := a.LagrangeG2(testParams.TauPowers*2, 2); err == nil {
+		t.Error("accepted a domain size larger than the powers")
+	}
+}
+
+func TestLagrangeFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "powersoftau")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	a := NewChallenge(testParams).Accumulator
+	g1, err := a.LagrangeG1(64, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g2, err := a.LagrangeG2(64, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, compressed := range []bool{false, true} {
+		filename := filepath.Join(dir, "lagrange")
+		if err := WriteLagrange(filename, g1, g2, compressed); err != nil {
+			t.Fatal(err)
+		}
+		h1, h2, err := ReadLagrange(filename, compressed, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(h1) != 64 || len(h2) != 64 {
+			t.Fatalf("read %d and %d points", len(h1), len(h2))
+		}
+		for i := range g1 {
+			if !bytes.Equal(h1[i].EncodeUncompressed(), g1[i].EncodeUncompressed()) ||
+				!bytes.Equal(h2[i].EncodeUncompressed(), g2[i].EncodeUncompressed()) {
+				t.Fatalf("point %d changed in the round-trip", i)
+			}
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package powersoftau
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/FiloSottile/powersoftau/bls12"
+	"golang.org/x/crypto/blake2b"
+)
+
+// Parameters describes the size of a ceremony, and of its files.
+type Parameters struct {
+	// Power is the base 2 logarithm of TauPowers.
+	Power uint
+
+	// TauPowers is the number of powers of tau in G2, and of alpha and
+	// beta times the powers of tau in G1.
+	TauPowers int
+
+	// TauPowersG1 is the number of powers of tau in G1, 2*TauPowers-1.
+	TauPowersG1 int
+
+	ChallengeSize int64
+	ResponseSize  int64
+}
+
+// PublicKeySize is the size of an encoded PublicKey.
+const PublicKeySize = 3*bls12.G2UncompressedSize + 6*bls12.G1UncompressedSize
+
+// MaxPower is the largest supported Power. The BLS12-381 scalar field has
+// 2^32 roots of unity, and TauG1 must fit in a FFT domain.
+const MaxPower = 31
+
+// DefaultPower is the Power of the original Powers of Tau ceremony.
+const DefaultPower = 21
+
+// NewParameters returns the Parameters of a ceremony with 2^power powers.
+func NewParameters(power uint) (*Parameters, error) {
+	if power < 1 || power > MaxPower {
+		return nil, fmt.Errorf("the power must be between 1 and %d", MaxPower)
+	}
+	p := &Parameters{Power: power}
+	p.TauPowers = 1 << power
+	p.TauPowersG1 = p.TauPowers<<1 - 1
+	n, n1 := int64(p.TauPowers), int64(p.TauPowersG1)
+	p.ChallengeSize = n1*bls12.G1UncompressedSize + // G1 powers
+		n*bls12.G2UncompressedSize + // G2 powers
+		n*bls12.G1UncompressedSize + // alpha powers
+		n*bls12.G1UncompressedSize + // beta powers
+		bls12.G2UncompressedSize + // beta
+		blake2b.Size
+	p.ResponseSize = n1*bls12.G1CompressedSize + // G1 powers
+		n*bls12.G2CompressedSize + // G2 powers
+		n*bls12.G1CompressedSize + // alpha powers
+		n*bls12.G1CompressedSize + // beta powers
+		bls12.G2CompressedSize + // beta
+		blake2b.Size + PublicKeySize
+	return p, nil
+}
+
+// DefaultParameters are the Parameters of the original Powers of Tau
+// ceremony, with 2^21 powers.
+var DefaultParameters, _ = NewParameters(DefaultPower)
+
+// ChallengeParameters returns the Parameters matching a challenge file of
+// the given size.
+func ChallengeParameters(size int64) (*Parameters, error) {
+	for power := uint(1); power <= MaxPower; power++ {
+		p, _ := NewParameters(power)
+		if p.ChallengeSize == size {
+			return p, nil
+		}
+	}
+	return nil, errors.New("the challenge file size does not match any ceremony size")
+}
+
+// ResponseParameters returns the Parameters matching a response file of
+// the given size.
+func ResponseParameters(size int64) (*Parameters, error) {
+	for power := uint(1); power <= MaxPower; power++ {
+		p, _ := NewParameters(power)
+		if p.ResponseSize == size {
+			return p, nil
+		}
+	}
+	return nil, errors.New("the response file size does not match any ceremony size")
+}
+
+// check returns an error if a does not have the size described by p.
+func (p *Parameters) check(a *Accumulator) error {
+	if len(a.TauG1) != p.TauPowersG1 || len(a.TauG2) != p.TauPowers ||
+		len(a.AlphaTau) != p.TauPowers || len(a.BetaTau) != p.TauPowers {
+		return fmt.Errorf("the accumulator does not have 2^%d powers", p.Power)
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package powersoftau
+
+import "testing"
+
+func TestParametersFromSize(t *testing.T) {
+	// The sizes of the files of the original ceremony.
+	if DefaultParameters.ChallengeSize != 1207959712 {
+		t.Errorf("wrong default challenge size: %d", DefaultParameters.ChallengeSize)
+	}
+	if DefaultParameters.ResponseSize != 603981040 {
+		t.Errorf("wrong default response size: %d", DefaultParameters.ResponseSize)
+	}
+
+	for _, power := range []uint{1, 10, DefaultPower, 28, MaxPower} {
+		p, err := NewParameters(power)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := ChallengeParameters(p.ChallengeSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := ResponseParameters(p.ResponseSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *c != *p || *r != *p {
+			t.Errorf("2^%d: detected the wrong parameters", power)
+		}
+	}
+
+	if _, err := ChallengeParameters(DefaultParameters.ChallengeSize + 1); err == nil {
+		t.Error("detected parameters for an invalid challenge size")
+	}
+	if _, err := ResponseParameters(DefaultParameters.ChallengeSize); err == nil {
+		t.Error("detected parameters for an invalid response size")
+	}
+	if _, err := NewParameters(MaxPower + 1); err == nil {
+		t.Error("accepted a power larger than MaxPower")
+	}
+}
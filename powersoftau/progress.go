@@ -0,0 +1,83 @@
+package powersoftau
+
+import (
+	"io"
+	"sync"
+)
+
+// A ProgressFunc is called as a long operation makes progress, with the
+// amount of work done so far out of total. The unit depends on the
+// operation: points for computations, and bytes for reading and writing
+// files. Calls are never concurrent, and done is never decreasing.
+type ProgressFunc func(done, total int64)
+
+// progress rate-limits and serializes calls to a ProgressFunc from
+// concurrent workers. A nil *progress does nothing.
+type progress struct {
+	f     ProgressFunc
+	total int64
+
+	mu       sync.Mutex
+	done     int64
+	reported int64
+}
+
+func newProgress(f ProgressFunc, total int64) *progress {
+	if f == nil {
+		return nil
+	}
+	return &progress{f: f, total: total, reported: -1}
+}
+
+// add records that n more units of work are done.
+func (p *progress) add(n int64) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.update(p.done + n)
+}
+
+// set records that done units of work are done.
+func (p *progress) set(done int64) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.update(done)
+}
+
+// update calls the function if done is at least 0.1% more than the last
+// reported value, or if it's the total. p.mu must be held.
+func (p *progress) update(done int64) {
+	p.done = done
+	if done == p.reported || done-p.reported < p.total/1000 && done != p.total {
+		return
+	}
+	p.reported = done
+	p.f(done, p.total)
+}
+
+type progressReader struct {
+	r io.Reader
+	p *progress
+}
+
+func (r progressReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	r.p.add(int64(n))
+	return n, err
+}
+
+type progressWriter struct {
+	w io.Writer
+	p *progress
+}
+
+func (w progressWriter) Write(b []byte) (int, error) {
+	n, err := w.w.Write(b)
+	w.p.add(int64(n))
+	return n, err
+}
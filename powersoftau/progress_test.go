@@ -0,0 +1,78 @@
+package powersoftau
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// recordProgress returns a ProgressFunc that checks its calls, and a
+// function that checks that the last one reported completion.
+func recordProgress(t *testing.T, name string) (ProgressFunc, func(total int64)) {
+	var calls int
+	last := int64(-1)
+	lastTotal := int64(-1)
+	f := func(done, total int64) {
+		calls++
+		if done <= last || done > total {
+			t.Errorf("%s: progress went from %d to %d of %d", name, last, done, total)
+		}
+		last, lastTotal = done, total
+	}
+	return f, func(total int64) {
+		if calls == 0 || calls > 1001 {
+			t.Errorf("%s: progress called %d times", name, calls)
+		}
+		if last != total || lastTotal != total {
+			t.Errorf("%s: progress ended at %d of %d, expected %d", name, last, lastTotal, total)
+		}
+	}
+}
+
+func TestProgress(t *testing.T) {
+	dir, err := ioutil.TempDir("", "powersoftau")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	challengeFile := filepath.Join(dir, "challenge")
+	writeTestChallenge(t, challengeFile, testParams)
+
+	f, check := recordProgress(t, "ReadChallengeProgress")
+	c, err := ReadChallengeProgress(challengeFile, nil, false, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(testParams.ChallengeSize)
+
+	f, check = recordProgress(t, "Compute")
+	c.Progress = f
+	if err := c.ComputeWith(4, fixedKeypair()); err != nil {
+		t.Fatal(err)
+	}
+	check(int64(testParams.TauPowersG1))
+
+	f, check = recordProgress(t, "WriteResponse")
+	c.Progress = f
+	if err := WriteResponse(filepath.Join(dir, "response"), c); err != nil {
+		t.Fatal(err)
+	}
+	check(testParams.ResponseSize)
+
+	f, check = recordProgress(t, "ReadResponseProgress")
+	if _, err := ReadResponseProgress(filepath.Join(dir, "response"), nil, false, f); err != nil {
+		t.Fatal(err)
+	}
+	check(testParams.ResponseSize)
+
+	f, check = recordProgress(t, "ComputeStream")
+	_, err = ComputeStream(challengeFile, filepath.Join(dir, "response2"), &StreamOptions{
+		NewKeypair: fixedKeypair(),
+		Progress:   f,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(int64(testParams.TauPowersG1 + 3*testParams.TauPowers + 1))
+}
@@ -2,6 +2,8 @@ package powersoftau
 
 import (
 	"crypto/rand"
+	"fmt"
+	"io"
 
 	"golang.org/x/crypto/blake2b"
 
@@ -18,29 +20,40 @@ type PublicKey struct {
 
 type PrivateKey struct {
 	Tau, Alpha, Beta []byte
+
+	// locked is the memory backing the scalars, if LockPrivateKeys was used.
+	locked []byte
 }
 
+// NewKeypair generates a keypair for the challenge with hash digest, using
+// randomness from crypto/rand.
 func NewKeypair(digest []byte) (*PublicKey, *PrivateKey) {
-	pub, priv := &PublicKey{}, &PrivateKey{}
+	return newKeypair(digest, RandomScalar, func() *bls12.EP {
+		return randomG1(RandomScalar())
+	})
+}
 
-	priv.Tau = randomScalar()
-	priv.Alpha = randomScalar()
-	priv.Beta = randomScalar()
+// newKeypair generates a keypair for digest, drawing tau, alpha and beta
+// from randomScalar, and then the S point of each proof of knowledge from
+// randomG1, in the same order as the Rust keypair function.
+func newKeypair(digest []byte, randomScalar func() *bls12.Fr, randomG1 func() *bls12.EP) (*PublicKey, *PrivateKey) {
+	pub := &PublicKey{}
+	var scalars [3][]byte
+	for i := range scalars {
+		k := randomScalar()
+		scalars[i] = k.Bytes(new([32]byte))
+		k.SetZero()
+	}
+	priv := newPrivateKey(scalars[0], scalars[1], scalars[2])
 
 	gen := func(x []byte, personalization byte) struct {
 		S     *bls12.EP
 		Sx    *bls12.EP
 		SxG2x *bls12.EP2
 	} {
-		s := randomScalar()
-		S := (&bls12.EP{}).ScalarBaseMult(s)
-		Sx := S.Copy().ScalarMult(x)
-		h, _ := blake2b.New512(nil)
-		h.Write([]byte{personalization})
-		h.Write(digest)
-		h.Write(S.EncodeUncompressed())
-		h.Write(Sx.EncodeUncompressed())
-		SxG2x := HashToG2(h.Sum(nil)).ScalarMult(x)
+		S := randomG1()
+		Sx := S.Copy().ScalarMultConstantTime(x)
+		SxG2x := computeG2s(digest, personalization, S, Sx).ScalarMultConstantTime(x)
 		return struct {
 			S     *bls12.EP
 			Sx    *bls12.EP
@@ -57,14 +70,86 @@ func NewKeypair(digest []byte) (*PublicKey, *PrivateKey) {
 	return pub, priv
 }
 
-func randomScalar() []byte {
+// Verify checks the proofs of knowledge of tau, alpha and beta that
+// NewKeypair generated for the challenge digest.
+func (p *PublicKey) Verify(digest []byte) error {
+	g2s, err := p.verify(digest)
+	if err != nil {
+		return err
+	}
+	for _, q := range g2s {
+		q.Close()
+	}
+	return nil
+}
+
+// verify is like Verify, but on success it also returns the G2 points the
+// tau, alpha and beta proofs were checked against, which the caller must
+// close.
+func (p *PublicKey) verify(digest []byte) ([3]*bls12.EP2, error) {
+	var g2s [3]*bls12.EP2
+	closeAll := func() {
+		for _, q := range g2s {
+			if q != nil {
+				q.Close()
+			}
+		}
+	}
+	for i, k := range []struct {
+		S     *bls12.EP
+		Sx    *bls12.EP
+		SxG2x *bls12.EP2
+	}{p.Tau, p.Alpha, p.Beta} {
+		name := [...]string{"tau", "alpha", "beta"}[i]
+		if k.S.IsZero() || k.Sx.IsZero() || k.SxG2x.IsZero() {
+			closeAll()
+			return g2s, fmt.Errorf("the %s proof of knowledge contains a point at infinity", name)
+		}
+		g2s[i] = computeG2s(digest, byte(i), k.S, k.Sx)
+		if !SameRatio(k.S, k.Sx, g2s[i], k.SxG2x) {
+			closeAll()
+			return g2s, fmt.Errorf("the %s proof of knowledge is invalid", name)
+		}
+	}
+	return g2s, nil
+}
+
+// computeG2s returns the G2 point that the proof of knowledge of x in
+// (S, Sx) is checked against, derived from the challenge digest.
+func computeG2s(digest []byte, personalization byte, S, Sx *bls12.EP) *bls12.EP2 {
+	h, _ := blake2b.New512(nil)
+	h.Write([]byte{personalization})
+	h.Write(digest)
+	h.Write(S.EncodeUncompressed())
+	h.Write(Sx.EncodeUncompressed())
+	return HashToG2(h.Sum(nil))
+}
+
+// RandomScalar returns a uniformly random scalar from crypto/rand. It panics
+// if crypto/rand fails.
+func RandomScalar() *bls12.Fr {
+	return readScalar(rand.Reader)
+}
+
+// readScalar reads a uniformly random scalar from r by rejection sampling.
+// It panics if r fails, as that would lead to a predictable private key.
+func readScalar(r io.Reader) *bls12.Fr {
+	var s [32]byte
+	defer ZeroBytes(s[:])
 	for {
-		s := make([]byte, 32)
-		if _, err := rand.Read(s); err != nil {
+		if _, err := io.ReadFull(r, s[:]); err != nil {
 			panic(err)
 		}
-		if bls12.IsScalar(s) {
-			return s
+		if k, err := (&bls12.Fr{}).SetBytes(s[:]); err == nil {
+			return k
 		}
 	}
 }
+
+// randomG1 returns the generator multiplied by k, and zeroes k.
+func randomG1(k *bls12.Fr) *bls12.EP {
+	var buf [32]byte
+	defer ZeroBytes(buf[:])
+	defer k.SetZero()
+	return (&bls12.EP{}).SetOne().ScalarMultConstantTime(k.Bytes(&buf))
+}
@@ -0,0 +1,35 @@
+package powersoftau
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/FiloSottile/powersoftau/bls12"
+)
+
+func TestPublicKeyVerify(t *testing.T) {
+	digest := make([]byte, 64)
+	digest[0] = 42
+	pub, _ := NewKeypair(digest)
+
+	if err := pub.Verify(digest); err != nil {
+		t.Fatalf("valid public key failed verification: %v", err)
+	}
+
+	otherDigest := make([]byte, 64)
+	if err := pub.Verify(otherDigest); err == nil {
+		t.Error("public key verified against the wrong digest")
+	}
+
+	sx := pub.Alpha.Sx
+	pub.Alpha.Sx = sx.Copy().Add((&bls12.EP{}).SetOne())
+	if err := pub.Verify(digest); err == nil || !strings.Contains(err.Error(), "alpha") {
+		t.Errorf("tampered alpha key: got error %v", err)
+	}
+	pub.Alpha.Sx = sx
+
+	pub.Beta.S = (&bls12.EP{}).SetZero()
+	if err := pub.Verify(digest); err == nil || !strings.Contains(err.Error(), "beta") {
+		t.Errorf("zero beta key: got error %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package powersoftau
+
+import (
+	"github.com/FiloSottile/powersoftau/bls12"
+)
+
+// SameRatio returns true if a/b == c/d, by checking e(a, d) == e(b, c).
+func SameRatio(a, b *bls12.EP, c, d *bls12.EP2) bool {
+	return bls12.PairingCheck([]*bls12.EP{a, b.Copy().Neg()}, []*bls12.EP2{d, c})
+}
+
+// SameRatioG1 returns true if every element of v is the previous one
+// multiplied by x, where g2x = x * g2.
+//
+// Instead of checking each pair, it computes with processes goroutines a
+// random linear combination s of v[:len(v)-1] and the combination sx of
+// v[1:] with the same coefficients, using multi-exponentiation, and checks
+// the ratio of s and sx with a single pairing check.
+func SameRatioG1(v []*bls12.EP, g2, g2x *bls12.EP2, processes int) bool {
+	if len(v) < 2 {
+		return true
+	}
+	s, sx := powerPairsG1(v, processes)
+	return SameRatio(s, sx, g2, g2x)
+}
+
+// SameRatioG2 is like SameRatioG1, but for G2 elements and a G1 ratio.
+func SameRatioG2(v []*bls12.EP2, g1, g1x *bls12.EP, processes int) bool {
+	if len(v) < 2 {
+		return true
+	}
+	s, sx := powerPairsG2(v, processes)
+	defer s.Close()
+	defer sx.Close()
+	return SameRatio(g1, g1x, s, sx)
+}
+
+func powerPairsG1(v []*bls12.EP, processes int) (s, sx *bls12.EP) {
+	scalars := randomScalars(len(v) - 1)
+	m := &bls12.MultiExp{Workers: processes}
+	return m.G1(v[:len(v)-1], scalars), m.G1(v[1:], scalars)
+}
+
+func powerPairsG2(v []*bls12.EP2, processes int) (s, sx *bls12.EP2) {
+	scalars := randomScalars(len(v) - 1)
+	m := &bls12.MultiExp{Workers: processes}
+	return m.G2(v[:len(v)-1], scalars), m.G2(v[1:], scalars)
+}
+
+func randomScalars(n int) [][]byte {
+	res := make([][]byte, n)
+	for i := range res {
+		res[i] = RandomScalar().Bytes(new([32]byte))
+	}
+	return res
+}
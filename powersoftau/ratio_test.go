@@ -0,0 +1,50 @@
+package powersoftau
+
+import (
+	"testing"
+
+	"github.com/FiloSottile/powersoftau/bls12"
+)
+
+func TestSameRatio(t *testing.T) {
+	const n = 3000 // more than one chunk
+	x := []byte{3}
+
+	g1 := make([]*bls12.EP, n)
+	g1[0] = (&bls12.EP{}).SetOne()
+	for i := 1; i < n; i++ {
+		g1[i] = g1[i-1].Copy().ScalarMult(x)
+	}
+	g2 := make([]*bls12.EP2, 20)
+	g2[0] = bls12.NewEP2().SetOne()
+	for i := 1; i < len(g2); i++ {
+		g2[i] = g2[i-1].Copy().ScalarMult(x)
+	}
+	defer func() {
+		for _, p := range g2 {
+			p.Close()
+		}
+	}()
+
+	if !SameRatioG1(g1, g2[0], g2[1], 4) {
+		t.Error("valid G1 powers failed")
+	}
+	if !SameRatioG2(g2, g1[0], g1[1], 4) {
+		t.Error("valid G2 powers failed")
+	}
+	if SameRatioG1(g1, g2[0], g2[2], 4) {
+		t.Error("G1 powers succeeded with the wrong ratio")
+	}
+	if SameRatioG2(g2, g1[0], g1[2], 4) {
+		t.Error("G2 powers succeeded with the wrong ratio")
+	}
+
+	g1[2500] = g1[2500].Copy().Add(g1[0])
+	if SameRatioG1(g1, g2[0], g2[1], 4) {
+		t.Error("tampered G1 powers succeeded")
+	}
+	g2[10].Add(g2[0])
+	if SameRatioG2(g2, g1[0], g1[1], 4) {
+		t.Error("tampered G2 powers succeeded")
+	}
+}
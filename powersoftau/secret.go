@@ -0,0 +1,90 @@
+package powersoftau
+
+import (
+	"fmt"
+
+	"github.com/FiloSottile/powersoftau/bls12"
+)
+
+// The private key is the toxic waste of the ceremony: if it leaked, the
+// contribution would be useless. Making sure it's destroyed is best effort,
+// as Go and RELIC can leave copies of intermediate values in memory we
+// don't control, but we zero all the copies we make once done with them.
+
+// lockKeys is set by LockPrivateKeys.
+var lockKeys bool
+
+// LockPrivateKeys makes all private keys generated from now on be stored
+// in memory locked with mlock, so that they are never written to swap. It
+// returns an error if that's not possible, for example because of
+// RLIMIT_MEMLOCK or because the platform doesn't support it.
+func LockPrivateKeys() error {
+	b, err := allocLocked(1)
+	if err != nil {
+		return err
+	}
+	freeLocked(b)
+	lockKeys = true
+	return nil
+}
+
+// newPrivateKey returns a PrivateKey holding tau, alpha and beta. If
+// LockPrivateKeys was called, they are copied into locked memory and the
+// arguments are zeroed.
+func newPrivateKey(tau, alpha, beta []byte) *PrivateKey {
+	if !lockKeys {
+		return &PrivateKey{Tau: tau, Alpha: alpha, Beta: beta}
+	}
+	mem, err := allocLocked(len(tau) + len(alpha) + len(beta))
+	if err != nil {
+		panic("powersoftau: failed to allocate locked memory: " + err.Error())
+	}
+	priv := &PrivateKey{locked: mem}
+	for _, s := range []struct {
+		dst *[]byte
+		src []byte
+	}{{&priv.Tau, tau}, {&priv.Alpha, alpha}, {&priv.Beta, beta}} {
+		n := copy(mem, s.src)
+		*s.dst, mem = mem[:n:n], mem[n:]
+		ZeroBytes(s.src)
+	}
+	return priv
+}
+
+// Destroy overwrites the private key with zeroes, and releases its locked
+// memory if any. The key must not be used afterwards.
+func (p *PrivateKey) Destroy() {
+	ZeroBytes(p.Tau)
+	ZeroBytes(p.Alpha)
+	ZeroBytes(p.Beta)
+	p.Tau, p.Alpha, p.Beta = nil, nil, nil
+	if p.locked != nil {
+		freeLocked(p.locked)
+		p.locked = nil
+	}
+}
+
+// ZeroBytes overwrites b with zeroes, to erase secrets once they are used.
+func ZeroBytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+// scalars returns the private key as field elements, which the caller
+// should zero with SetZero once done.
+func (p *PrivateKey) scalars() (tau, alpha, beta *bls12.Fr, err error) {
+	if tau, err = (&bls12.Fr{}).SetBytes(p.Tau); err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid private key: %v", err)
+	}
+	if alpha, err = (&bls12.Fr{}).SetBytes(p.Alpha); err != nil {
+		tau.SetZero()
+		return nil, nil, nil, fmt.Errorf("invalid private key: %v", err)
+	}
+	if beta, err = (&bls12.Fr{}).SetBytes(p.Beta); err != nil {
+		tau.SetZero()
+		alpha.SetZero()
+		return nil, nil, nil, fmt.Errorf("invalid private key: %v", err)
+	}
+	return tau, alpha, beta, nil
+}
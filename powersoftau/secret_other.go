@@ -0,0 +1,12 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package powersoftau
+
+import "errors"
+
+func allocLocked(n int) ([]byte, error) {
+	return nil, errors.New("locking memory is not supported on this platform")
+}
+
+func freeLocked(b []byte) {}
@@ -0,0 +1,44 @@
+package powersoftau
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPrivateKeyDestroy(t *testing.T) {
+	_, priv := NewKeypair(make([]byte, 64))
+	tau, alpha, beta := priv.Tau, priv.Alpha, priv.Beta
+	priv.Destroy()
+	for _, s := range [][]byte{tau, alpha, beta} {
+		if !bytes.Equal(s, make([]byte, len(s))) {
+			t.Errorf("scalar not zeroed: %x", s)
+		}
+	}
+	if priv.Tau != nil || priv.Alpha != nil || priv.Beta != nil {
+		t.Error("the private key still references the scalars")
+	}
+}
+
+func TestLockPrivateKeys(t *testing.T) {
+	defer func(l bool) { lockKeys = l }(lockKeys)
+	if err := LockPrivateKeys(); err != nil {
+		t.Skipf("can't lock memory: %v", err)
+	}
+	digest := make([]byte, 64)
+	pub, priv := NewKeypair(digest)
+	if priv.locked == nil {
+		t.Fatal("the private key is not in locked memory")
+	}
+	if err := pub.Verify(digest); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range [][]byte{priv.Tau, priv.Alpha, priv.Beta} {
+		if len(s) != 32 || bytes.Equal(s, make([]byte, 32)) {
+			t.Errorf("invalid scalar in locked memory: %x", s)
+		}
+	}
+	priv.Destroy()
+	if priv.locked != nil {
+		t.Error("the locked memory was not released")
+	}
+}
@@ -0,0 +1,32 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package powersoftau
+
+import (
+	"os"
+	"syscall"
+)
+
+// allocLocked returns n bytes of memory outside the Go heap, locked with
+// mlock so that it's never swapped out.
+func allocLocked(n int) ([]byte, error) {
+	size := (n + os.Getpagesize() - 1) / os.Getpagesize() * os.Getpagesize()
+	b, err := syscall.Mmap(-1, 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_ANON|syscall.MAP_PRIVATE)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Mlock(b); err != nil {
+		syscall.Munmap(b)
+		return nil, err
+	}
+	return b[:n], nil
+}
+
+// freeLocked zeroes and releases memory returned by allocLocked.
+func freeLocked(b []byte) {
+	b = b[:cap(b)]
+	ZeroBytes(b)
+	syscall.Munlock(b)
+	syscall.Munmap(b)
+}
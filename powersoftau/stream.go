@@ -0,0 +1,584 @@
+package powersoftau
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/FiloSottile/powersoftau/bls12"
+	"golang.org/x/crypto/blake2b"
+)
+
+// StreamOptions configures ComputeStream.
+type StreamOptions struct {
+	// Params is the size of the ceremony. If nil, it's detected from the
+	// size of the challenge file.
+	Params *Parameters
+
+	// NextFile, if not empty, is where the next challenge is written.
+	NextFile string
+
+	// Processes is the number of goroutines performing the computation.
+	// If zero, runtime.NumCPU() is used.
+	Processes int
+
+	// NewKeypair, if not nil, is used instead of the package NewKeypair,
+	// for example to make a beacon contribution with BeaconKeypair.
+	NewKeypair func(digest []byte) (*PublicKey, *PrivateKey)
+
+	// Checkpoint, if not empty, is the path of a file where the keypair and
+	// the progress are periodically saved. It contains the sealed private
+	// key, and is erased once the computation completes successfully. If the
+	// computation fails, the partial outputs are kept to resume from.
+	Checkpoint string
+
+	// Passphrase is required with Checkpoint. The private key is stored in
+	// the checkpoint sealed under a key derived from it, and resuming
+	// requires the same passphrase.
+	Passphrase []byte
+
+	// Resume continues an interrupted computation from Checkpoint instead
+	// of starting a new one, and produces the same response the
+	// uninterrupted computation would have.
+	Resume bool
+
+	// Progress, if not nil, is called as points are written to the
+	// response, with the total number of points in the accumulator.
+	Progress ProgressFunc
+}
+
+// checkpointInterval is how often progress is saved to the checkpoint.
+var checkpointInterval = time.Minute
+
+// ComputeStream reads the challenge file, performs a contribution, and
+// writes the response file, producing the same output as ReadChallenge,
+// Compute, WriteResponse and WriteNextChallenge.
+//
+// Unlike those, it never holds the whole accumulator in memory: points are
+// read, multiplied and written in chunks, and memory use is bounded by the
+// chunk size times the number of processes.
+//
+// The outputs are written to files with a ".partial" suffix, which are
+// renamed once complete, so a partial response is never left behind.
+//
+// The returned Challenge has all hashes, the Parameters and the PublicKey
+// set, but a nil Accumulator.
+func ComputeStream(challengeFile, responseFile string, opts *StreamOptions) (*Challenge, error) {
+	return ComputeStreamContext(context.Background(), challengeFile, responseFile, opts)
+}
+
+// ComputeStreamContext is like ComputeStream, but stops the workers and
+// returns ctx.Err() when ctx is canceled. If opts.Checkpoint is set, the
+// computation can then be resumed.
+func ComputeStreamContext(ctx context.Context, challengeFile, responseFile string, opts *StreamOptions) (*Challenge, error) {
+	newKeypair := NewKeypair
+	if opts != nil && opts.NewKeypair != nil {
+		newKeypair = opts.NewKeypair
+	}
+	return computeStream(ctx, challengeFile, responseFile, opts, newKeypair)
+}
+
+// partialSuffix is appended to the names of the outputs of ComputeStream
+// while they are being written.
+const partialSuffix = ".partial"
+
+func computeStream(ctx context.Context, challengeFile, responseFile string, opts *StreamOptions,
+	newKeypair func(digest []byte) (*PublicKey, *PrivateKey)) (c *Challenge, err error) {
+	if opts == nil {
+		opts = &StreamOptions{}
+	}
+	processes := opts.Processes
+	if processes == 0 {
+		processes = runtime.NumCPU()
+	}
+	if opts.Resume && opts.Checkpoint == "" {
+		return nil, errors.New("resuming requires a checkpoint file")
+	}
+	if opts.Checkpoint != "" && len(opts.Passphrase) == 0 {
+		return nil, errors.New("a checkpoint requires a passphrase")
+	}
+
+	f, err := os.Open(challengeFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	params := opts.Params
+	if params == nil {
+		params, err = ChallengeParameters(fi.Size())
+		if err != nil {
+			return nil, err
+		}
+	}
+	if fi.Size() != params.ChallengeSize {
+		return nil, errors.New("the challenge file has the wrong size")
+	}
+
+	// The proofs of knowledge depend on the hash of the whole challenge,
+	// so we need to read it once before starting. It's hashed again as it's
+	// streamed, and checked at the end, in case it changed in between.
+	h, _ := blake2b.New512(nil)
+	if _, err := io.Copy(h, contextReader{ctx, f}); err != nil {
+		return nil, err
+	}
+	c = &Challenge{
+		PreviousHash:  make([]byte, blake2b.Size),
+		ChallengeHash: h.Sum(nil),
+		Parameters:    params,
+	}
+	if _, err := f.ReadAt(c.PreviousHash, 0); err != nil {
+		return nil, err
+	}
+
+	var pub *PublicKey
+	var priv *PrivateKey
+	var cp *checkpoint
+	var startSection, startDone int
+	if opts.Resume {
+		cp, pub, priv, startSection, startDone, err = openCheckpoint(opts.Checkpoint, c.ChallengeHash, opts.Passphrase, params)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		pub, priv = newKeypair(c.ChallengeHash)
+		if opts.Checkpoint != "" {
+			cp, err = createCheckpoint(opts.Checkpoint, c.ChallengeHash, opts.Passphrase, pub, priv)
+			if os.IsExist(err) {
+				return nil, fmt.Errorf("checkpoint %s already exists, resume or remove it", opts.Checkpoint)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	defer priv.Destroy()
+	if cp != nil {
+		defer cp.Close()
+	}
+	c.PublicKey = pub
+
+	tau, alpha, beta, err := priv.scalars()
+	if err != nil {
+		return nil, err
+	}
+	defer tau.SetZero()
+	defer alpha.SetZero()
+	defer beta.SetZero()
+	sections := accumulatorSections(params, alpha, beta)
+
+	inOff, outOff := sectionOffsets(sections, startSection, startDone)
+	nextOff := inOff
+	if startSection == 0 && startDone == 0 {
+		// Nothing was written yet, start the output files from scratch.
+		outOff, nextOff = 0, 0
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	ih, _ := blake2b.New512(nil)
+	if _, err := io.CopyN(ih, contextReader{ctx, f}, inOff); err != nil {
+		return nil, err
+	}
+
+	// Without a checkpoint, there is no way to use partial outputs.
+	if cp == nil {
+		defer func() {
+			if err != nil {
+				os.Remove(responseFile + partialSuffix)
+				if opts.NextFile != "" {
+					os.Remove(opts.NextFile + partialSuffix)
+				}
+			}
+		}()
+	}
+
+	rh, _ := blake2b.New512(nil)
+	out, err := openOutput(responseFile+partialSuffix, outOff, rh)
+	if err != nil {
+		return nil, err
+	}
+	defer out.Close()
+	bw := bufio.NewWriter(out)
+	w := io.MultiWriter(bw, rh)
+	if outOff == 0 {
+		if _, err := w.Write(c.ChallengeHash); err != nil {
+			return nil, err
+		}
+	}
+
+	var next *os.File
+	var nw *bufio.Writer
+	var nextW io.Writer
+	if opts.NextFile != "" {
+		next, err = openOutput(opts.NextFile+partialSuffix, nextOff, nil)
+		if err != nil {
+			return nil, err
+		}
+		defer next.Close()
+		nw = bufio.NewWriter(next)
+		nextW = nw
+		if nextOff == 0 {
+			// The hash of the response will be filled in at the end.
+			if _, err := nw.Write(make([]byte, blake2b.Size)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var total, before int64
+	for i, s := range sections {
+		if i < startSection {
+			before += int64(s.n)
+		}
+		total += int64(s.n)
+	}
+	progress := newProgress(opts.Progress, total)
+	progress.set(before + int64(startDone))
+
+	in := bufio.NewReader(io.TeeReader(contextReader{ctx, f}, ih))
+	lastCheckpoint := time.Now()
+	for i, s := range sections[startSection:] {
+		i += startSection
+		start := 0
+		if i == startSection {
+			start = startDone
+		}
+		var written func(done int) error
+		if cp != nil || progress != nil {
+			written = func(done int) error {
+				progress.set(before + int64(done))
+				if cp == nil || time.Since(lastCheckpoint) < checkpointInterval {
+					return nil
+				}
+				lastCheckpoint = time.Now()
+				if err := bw.Flush(); err != nil {
+					return err
+				}
+				if err := out.Sync(); err != nil {
+					return err
+				}
+				if next != nil {
+					if err := nw.Flush(); err != nil {
+						return err
+					}
+					if err := next.Sync(); err != nil {
+						return err
+					}
+				}
+				return cp.update(i, done)
+			}
+		}
+		if err := s.stream(in, w, nextW, tau, processes, start, written); err != nil {
+			return nil, err
+		}
+		before += int64(s.n)
+	}
+	if !bytes.Equal(ih.Sum(nil), c.ChallengeHash) {
+		if cp != nil {
+			// The partial outputs can't be trusted anymore.
+			cp.erase()
+			os.Remove(responseFile + partialSuffix)
+			if opts.NextFile != "" {
+				os.Remove(opts.NextFile + partialSuffix)
+			}
+		}
+		return nil, errors.New("the challenge file changed while it was being read")
+	}
+
+	if err := pub.WriteTo(w); err != nil {
+		return nil, err
+	}
+	if err := bw.Flush(); err != nil {
+		return nil, err
+	}
+	if err := out.Sync(); err != nil {
+		return nil, err
+	}
+	if err := out.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(responseFile+partialSuffix, responseFile); err != nil {
+		return nil, err
+	}
+	c.ResponseHash = rh.Sum(nil)
+
+	if next != nil {
+		if err := nw.Flush(); err != nil {
+			return nil, err
+		}
+		if _, err := next.WriteAt(c.ResponseHash, 0); err != nil {
+			return nil, err
+		}
+		if err := next.Sync(); err != nil {
+			return nil, err
+		}
+		if err := next.Close(); err != nil {
+			return nil, err
+		}
+		if err := os.Rename(opts.NextFile+partialSuffix, opts.NextFile); err != nil {
+			return nil, err
+		}
+	}
+
+	if cp != nil {
+		if err := cp.erase(); err != nil {
+			return nil, fmt.Errorf("failed to erase the checkpoint: %v", err)
+		}
+	}
+
+	return c, nil
+}
+
+// openOutput opens filename for writing at offset. If offset is zero the
+// file is created or truncated, otherwise it must already be at least that
+// long, is truncated to offset, and its contents are written to h if not nil.
+func openOutput(filename string, offset int64, h io.Writer) (*os.File, error) {
+	if offset == 0 {
+		return os.Create(filename)
+	}
+	f, err := os.OpenFile(filename, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if fi.Size() < offset {
+		f.Close()
+		return nil, fmt.Errorf("%s is shorter than recorded in the checkpoint", filename)
+	}
+	if err := f.Truncate(offset); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if h != nil {
+		if _, err := io.Copy(h, f); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+func accumulatorSections(params *Parameters, alpha, beta *bls12.Fr) []section {
+	one := (&bls12.Fr{}).SetOne()
+	return []section{
+		{"TauG1", false, params.TauPowersG1, one},
+		{"TauG2", true, params.TauPowers, one},
+		{"AlphaTau", false, params.TauPowers, alpha},
+		{"BetaTau", false, params.TauPowers, beta},
+		{"BetaG2", true, 1, beta},
+	}
+}
+
+// sectionOffsets returns the offsets in the challenge and response files of
+// the point with index done in sections[section]. The offset in the next
+// challenge file is the same as in the challenge file.
+func sectionOffsets(sections []section, section, done int) (in, out int64) {
+	in, out = blake2b.Size, blake2b.Size
+	for i, s := range sections[:section+1] {
+		n := s.n
+		if i == section {
+			n = done
+		}
+		if s.g2 {
+			in += int64(n * bls12.G2UncompressedSize)
+			out += int64(n * bls12.G2CompressedSize)
+		} else {
+			in += int64(n * bls12.G1UncompressedSize)
+			out += int64(n * bls12.G1CompressedSize)
+		}
+	}
+	return in, out
+}
+
+// section is a vector of points in the accumulator, the i-th of which is
+// multiplied by coeff * tau^i during a contribution.
+type section struct {
+	name  string
+	g2    bool
+	n     int
+	coeff *bls12.Fr
+}
+
+type chunkResult struct {
+	n                        int
+	compressed, uncompressed []byte
+	err                      error
+}
+
+// stream reads the uncompressed points of s starting at index start from r,
+// and writes them multiplied and compressed to w, and uncompressed to next
+// if not nil. Chunks are processed by processes goroutines, and written in
+// order. After each chunk, written is called if not nil with the number of
+// points of s written so far.
+func (s *section) stream(r io.Reader, w, next io.Writer, tau *bls12.Fr, processes, start int,
+	written func(done int) error) error {
+	size := bls12.G1UncompressedSize
+	if s.g2 {
+		size = bls12.G2UncompressedSize
+	}
+
+	type job struct {
+		a   int
+		in  []byte
+		out chan chunkResult
+	}
+	done := start
+	jobs := make(chan job)
+	pending := make(chan chan chunkResult, processes)
+	quit := make(chan struct{})
+	// On early return, wait for the workers to finish, as the caller will
+	// zero tau.
+	var workers sync.WaitGroup
+	defer workers.Wait()
+	defer close(quit)
+
+	for i := 0; i < processes; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for j := range jobs {
+				j.out <- s.compute(j.in, j.a, tau, next != nil)
+			}
+		}()
+	}
+
+	go func() {
+		defer close(pending)
+		defer close(jobs)
+		chunk := 1 << 10
+		for a := start; a < s.n; a += chunk {
+			b := a + chunk
+			if b > s.n {
+				b = s.n
+			}
+			in := make([]byte, (b-a)*size)
+			out := make(chan chunkResult, 1)
+			_, err := io.ReadFull(r, in)
+			if err != nil {
+				out <- chunkResult{err: err}
+			} else {
+				select {
+				case jobs <- job{a, in, out}:
+				case <-quit:
+					return
+				}
+			}
+			select {
+			case pending <- out:
+			case <-quit:
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	for out := range pending {
+		res := <-out
+		if res.err != nil {
+			return res.err
+		}
+		if _, err := w.Write(res.compressed); err != nil {
+			return err
+		}
+		if next != nil {
+			if _, err := next.Write(res.uncompressed); err != nil {
+				return err
+			}
+		}
+		done += res.n
+		if written != nil {
+			if err := written(done); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// compute decodes the uncompressed points in in, which start at index a,
+// multiplies them, and encodes them compressed and, if uncompressed is
+// true, uncompressed. The points are always checked to be in the prime order
+// subgroup, as ScalarMultConstantTime is only correct there.
+func (s *section) compute(in []byte, a int, tau *bls12.Fr, uncompressed bool) chunkResult {
+	var k bls12.Fr
+	defer k.SetZero()
+	var buf [32]byte
+	defer ZeroBytes(buf[:])
+	k.Set(tau).Exp(exponent(a)).Mul(s.coeff)
+
+	var res chunkResult
+	if s.g2 {
+		var points []*bls12.EP2
+		defer func() {
+			for _, p := range points {
+				p.Close()
+			}
+		}()
+		for i := a; len(in) > 0; i++ {
+			p := bls12.NewEP2()
+			points = append(points, p)
+			if _, err := p.DecodeUncompressed(in[:bls12.G2UncompressedSize]); err != nil {
+				return chunkResult{err: fmt.Errorf("invalid G2 point %s[%d]: %v", s.name, i, err)}
+			}
+			if !p.IsInSubgroup() {
+				return chunkResult{err: fmt.Errorf("invalid G2 point %s[%d]: not in the prime order subgroup", s.name, i)}
+			}
+			p.ScalarMultConstantTime(k.Bytes(&buf))
+			in = in[bls12.G2UncompressedSize:]
+			k.Mul(tau)
+		}
+		bls12.NormalizeBatchG2(points)
+		for _, p := range points {
+			res.compressed = append(res.compressed, p.EncodeCompressed()...)
+			if uncompressed {
+				res.uncompressed = append(res.uncompressed, p.EncodeUncompressed()...)
+			}
+		}
+		res.n = len(points)
+	} else {
+		var points []*bls12.EP
+		for i := a; len(in) > 0; i++ {
+			p, err := (&bls12.EP{}).DecodeUncompressed(in[:bls12.G1UncompressedSize])
+			if err != nil {
+				return chunkResult{err: fmt.Errorf("invalid G1 point %s[%d]: %v", s.name, i, err)}
+			}
+			if !p.IsInSubgroup() {
+				return chunkResult{err: fmt.Errorf("invalid G1 point %s[%d]: not in the prime order subgroup", s.name, i)}
+			}
+			p.ScalarMultConstantTime(k.Bytes(&buf))
+			points = append(points, p)
+			in = in[bls12.G1UncompressedSize:]
+			k.Mul(tau)
+		}
+		bls12.NormalizeBatchG1(points)
+		for _, p := range points {
+			res.compressed = append(res.compressed, p.EncodeCompressed()...)
+			if uncompressed {
+				res.uncompressed = append(res.uncompressed, p.EncodeUncompressed()...)
+			}
+		}
+		res.n = len(points)
+	}
+	return res
+}
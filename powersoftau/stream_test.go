@@ -0,0 +1,338 @@
+package powersoftau
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/FiloSottile/powersoftau/bls12"
+	"golang.org/x/crypto/blake2b"
+)
+
+// testParams are the Parameters of a ceremony large enough to span
+// multiple chunks.
+var testParams, _ = NewParameters(11)
+
+// writeTestChallenge writes a valid challenge, as if from a previous
+// contribution with tau = 5, alpha = 7 and beta = 11.
+func writeTestChallenge(t *testing.T, filename string, params *Parameters) {
+	r := (&big.Int{}).SetBytes(bls12.ScalarOrder())
+	tau, alpha, beta := big.NewInt(5), big.NewInt(7), big.NewInt(11)
+
+	a := &Accumulator{}
+	k := big.NewInt(1)
+	for i := 0; i < params.TauPowersG1; i++ {
+		a.TauG1 = append(a.TauG1, (&bls12.EP{}).SetOne().ScalarMult(k.Bytes()))
+		if i < params.TauPowers {
+			a.TauG2 = append(a.TauG2, bls12.NewEP2().SetOne().ScalarMult(k.Bytes()))
+			ka := (&big.Int{}).Mul(k, alpha)
+			a.AlphaTau = append(a.AlphaTau, (&bls12.EP{}).SetOne().ScalarMult(ka.Mod(ka, r).Bytes()))
+			kb := (&big.Int{}).Mul(k, beta)
+			a.BetaTau = append(a.BetaTau, (&bls12.EP{}).SetOne().ScalarMult(kb.Mod(kb, r).Bytes()))
+		}
+		k.Mul(k, tau).Mod(k, r)
+	}
+	a.BetaG2 = bls12.NewEP2().SetOne().ScalarMult(beta.Bytes())
+
+	c := &Challenge{ResponseHash: make([]byte, blake2b.Size), Parameters: params, Accumulator: a}
+	c.ResponseHash[0] = 0xff
+	if err := WriteNextChallenge(filename, c); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// fixedKeypair returns a NewKeypair replacement that generates a keypair
+// on the first call and then always returns it. Each call returns a new
+// copy of the private key, since the callers destroy it.
+func fixedKeypair() func(digest []byte) (*PublicKey, *PrivateKey) {
+	var pub *PublicKey
+	var priv *PrivateKey
+	return func(digest []byte) (*PublicKey, *PrivateKey) {
+		if pub == nil {
+			pub, priv = NewKeypair(digest)
+		}
+		return pub, &PrivateKey{
+			Tau:   append([]byte{}, priv.Tau...),
+			Alpha: append([]byte{}, priv.Alpha...),
+			Beta:  append([]byte{}, priv.Beta...),
+		}
+	}
+}
+
+func TestComputeStream(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "powersoftau")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	challengeFile := filepath.Join(dir, "challenge")
+	writeTestChallenge(t, challengeFile, testParams)
+
+	newKeypair := fixedKeypair()
+
+	c, err := ReadChallenge(challengeFile, testParams, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.ComputeWith(4, newKeypair); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteResponse(filepath.Join(dir, "response1"), c); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteNextChallenge(filepath.Join(dir, "next1"), c); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := computeStream(context.Background(), challengeFile, filepath.Join(dir, "response2"), &StreamOptions{
+		NextFile: filepath.Join(dir, "next2"), Processes: 3, Params: testParams,
+	}, newKeypair)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(c.ChallengeHash, s.ChallengeHash) {
+		t.Error("different challenge hash")
+	}
+	if !bytes.Equal(c.ResponseHash, s.ResponseHash) {
+		t.Error("different response hash")
+	}
+	for _, name := range []string{"response", "next"} {
+		a, err := ioutil.ReadFile(filepath.Join(dir, name+"1"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ioutil.ReadFile(filepath.Join(dir, name+"2"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(a, b) {
+			t.Errorf("%s files are different", name)
+		}
+	}
+
+	r, err := ReadResponse(filepath.Join(dir, "response2"), nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch, err := ReadChallenge(challengeFile, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ch.Verify(r, 2); err != nil {
+		t.Errorf("streamed response failed verification: %v", err)
+	}
+}
+
+func TestComputeStreamResume(t *testing.T) {
+	defer func(d time.Duration) { checkpointInterval = d }(checkpointInterval)
+	checkpointInterval = 0
+	defer func(n int) { checkpointIterations = n }(checkpointIterations)
+	checkpointIterations = 1000
+	passphrase := []byte("correct horse battery staple")
+
+	dir, err := ioutil.TempDir("", "powersoftau")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	challengeFile := filepath.Join(dir, "challenge")
+	writeTestChallenge(t, challengeFile, testParams)
+	checkpointFile := filepath.Join(dir, "checkpoint")
+
+	newKeypair := fixedKeypair()
+	c, err := computeStream(context.Background(), challengeFile, filepath.Join(dir, "response1"), &StreamOptions{
+		NextFile: filepath.Join(dir, "next1"), Processes: 3,
+		Checkpoint: checkpointFile, Passphrase: passphrase,
+	}, newKeypair)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(checkpointFile); !os.IsNotExist(err) {
+		t.Fatalf("the checkpoint was not removed: %v", err)
+	}
+	response, err := ioutil.ReadFile(filepath.Join(dir, "response1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	next, err := ioutil.ReadFile(filepath.Join(dir, "next1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulate an interruption in the middle of AlphaTau, after some more
+	// points than recorded in the checkpoint were written to the partial
+	// outputs.
+	pub, priv := newKeypair(c.ChallengeHash)
+	cp, err := createCheckpoint(checkpointFile, c.ChallengeHash, passphrase, pub, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cp.update(2, 1024); err != nil {
+		t.Fatal(err)
+	}
+	cp.Close()
+	inOff, outOff := sectionOffsets(accumulatorSections(testParams, nil, nil), 2, 1024)
+	garbage := bytes.Repeat([]byte{0x42}, 1000)
+	if err := ioutil.WriteFile(filepath.Join(dir, "response2"+partialSuffix),
+		append(append([]byte{}, response[:outOff]...), garbage...), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "next2"+partialSuffix),
+		append(append([]byte{}, next[:inOff]...), garbage...), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := computeStream(context.Background(), challengeFile, filepath.Join(dir, "response2"), &StreamOptions{
+		NextFile: filepath.Join(dir, "next2"), Processes: 2,
+		Checkpoint: checkpointFile, Passphrase: passphrase, Resume: true,
+	}, func(digest []byte) (*PublicKey, *PrivateKey) {
+		t.Fatal("a new keypair was generated while resuming")
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(c.ResponseHash, s.ResponseHash) {
+		t.Error("different response hash")
+	}
+	for _, name := range []string{"response", "next"} {
+		a, err := ioutil.ReadFile(filepath.Join(dir, name+"1"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ioutil.ReadFile(filepath.Join(dir, name+"2"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(a, b) {
+			t.Errorf("%s files are different", name)
+		}
+	}
+	if _, err := os.Stat(checkpointFile); !os.IsNotExist(err) {
+		t.Errorf("the checkpoint was not removed: %v", err)
+	}
+}
+
+// notInSubgroupG1 and notInSubgroupG2 return a point on the curve that is
+// not in the prime order subgroup, like the bls12 subgroup tests.
+func notInSubgroupG1(t *testing.T) *bls12.EP {
+	for x := 1; x < 20; x++ {
+		buf := make([]byte, bls12.G1CompressedSize)
+		buf[0] = 1 << 7
+		buf[len(buf)-1] = byte(x)
+		if p, err := (&bls12.EP{}).DecodeCompressed(buf); err == nil && !p.IsInSubgroup() {
+			return p
+		}
+	}
+	t.Fatal("no valid x found")
+	return nil
+}
+
+func notInSubgroupG2(t *testing.T) *bls12.EP2 {
+	for x := 1; x < 20; x++ {
+		buf := make([]byte, bls12.G2CompressedSize)
+		buf[0] = 1 << 7
+		buf[len(buf)-1] = byte(x)
+		p := bls12.NewEP2()
+		if _, err := p.DecodeCompressed(buf); err == nil && !p.IsInSubgroup() {
+			return p
+		}
+		p.Close()
+	}
+	t.Fatal("no valid x found")
+	return nil
+}
+
+func TestComputeNotInSubgroup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "powersoftau")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	challengeFile := filepath.Join(dir, "challenge")
+	writeTestChallenge(t, challengeFile, testParams)
+
+	for _, tc := range []struct {
+		name   string
+		tamper func(a *Accumulator)
+	}{
+		{"TauG1", func(a *Accumulator) { a.TauG1[1500] = notInSubgroupG1(t) }},
+		{"TauG2", func(a *Accumulator) { a.TauG2[3] = notInSubgroupG2(t) }},
+		{"AlphaTau", func(a *Accumulator) { a.AlphaTau[0] = notInSubgroupG1(t) }},
+		{"BetaTau", func(a *Accumulator) { a.BetaTau[1023] = notInSubgroupG1(t) }},
+		{"BetaG2", func(a *Accumulator) { a.BetaG2 = notInSubgroupG2(t) }},
+	} {
+		c, err := ReadChallenge(challengeFile, testParams, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tc.tamper(c.Accumulator)
+		c.ResponseHash = make([]byte, blake2b.Size)
+		badFile := filepath.Join(dir, "challenge"+tc.name)
+		if err := WriteNextChallenge(badFile, c); err != nil {
+			t.Fatal(err)
+		}
+
+		c, err = ReadChallenge(badFile, testParams, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tauG1 := c.Accumulator.TauG1[1].EncodeUncompressed()
+		if err := c.Compute(2); err == nil {
+			t.Errorf("%s: Compute accepted a point not in the subgroup", tc.name)
+		}
+		if !bytes.Equal(c.Accumulator.TauG1[1].EncodeUncompressed(), tauG1) || c.PublicKey != nil {
+			t.Errorf("%s: Compute changed the accumulator", tc.name)
+		}
+
+		if _, err := computeStream(context.Background(), badFile, filepath.Join(dir, "response"+tc.name), &StreamOptions{
+			Processes: 2, Params: testParams,
+		}, NewKeypair); err == nil {
+			t.Errorf("%s: ComputeStream accepted a point not in the subgroup", tc.name)
+		}
+	}
+}
+
+func TestComputeStreamChallengeChanged(t *testing.T) {
+	dir, err := ioutil.TempDir("", "powersoftau")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	challengeFile := filepath.Join(dir, "challenge")
+	writeTestChallenge(t, challengeFile, testParams)
+
+	// Replace BetaG2 with the generator after the challenge was hashed, but
+	// before it's streamed.
+	f, err := os.OpenFile(challengeFile, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	one := bls12.NewEP2().SetOne()
+	defer one.Close()
+	var changed bool
+	_, err = ComputeStream(challengeFile, filepath.Join(dir, "response"), &StreamOptions{
+		NextFile: filepath.Join(dir, "next"), Processes: 2,
+		Progress: func(done, total int64) {
+			if !changed {
+				changed = true
+				if _, err := f.WriteAt(one.EncodeUncompressed(), testParams.ChallengeSize-bls12.G2UncompressedSize); err != nil {
+					t.Fatal(err)
+				}
+			}
+		},
+	})
+	if err == nil || !strings.Contains(err.Error(), "changed") {
+		t.Errorf("expected an error about the challenge changing, got %v", err)
+	}
+	checkNoFiles(t, dir, "challenge")
+}
@@ -0,0 +1,450 @@
+package powersoftau
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+
+	"github.com/FiloSottile/powersoftau/bls12"
+	"golang.org/x/crypto/blake2b"
+)
+
+// TranscriptOptions configures NewTranscript.
+type TranscriptOptions struct {
+	// Params is the size of the ceremony. If nil, it's detected from the
+	// size of the initial challenge file.
+	Params *Parameters
+
+	// Processes is the number of goroutines performing the verification.
+	// If zero, runtime.NumCPU() is used.
+	Processes int
+
+	// CheckSubgroup enables checking that all response points are in the
+	// prime order subgroup, like in ReadResponse.
+	CheckSubgroup bool
+}
+
+// A Transcript verifies a chain of contributions, one response at a time,
+// starting from a challenge.
+//
+// Each response is checked against the challenge that WriteNextChallenge
+// would make from the previous one, without writing it or holding any
+// accumulator in memory: the checks of Verify are performed on chunks of
+// points as they are read, and only the first points of the last
+// accumulator are kept.
+type Transcript struct {
+	// ChallengeHash is the hash of the current challenge, the one the next
+	// response must be based on.
+	ChallengeHash []byte
+
+	// Contributions is the number of responses verified so far.
+	Contributions int
+
+	params *Parameters
+	opts   TranscriptOptions
+	head   *accumulatorHead
+}
+
+// transcriptChunk is the number of points processed at a time by
+// Transcript. It's larger than the one of ComputeStream because the
+// multi-exponentiations are faster on more points.
+var transcriptChunk = 1 << 16
+
+// NewTranscript returns a Transcript starting from the challenge file. The
+// initial challenge is trusted, and can be compared with NewChallengeHash.
+func NewTranscript(challengeFile string, opts *TranscriptOptions) (*Transcript, error) {
+	t := &Transcript{}
+	if opts != nil {
+		t.opts = *opts
+	}
+	if t.opts.Processes == 0 {
+		t.opts.Processes = runtime.NumCPU()
+	}
+
+	f, err := os.Open(challengeFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	t.params = t.opts.Params
+	if t.params == nil {
+		t.params, err = ChallengeParameters(fi.Size())
+		if err != nil {
+			return nil, err
+		}
+	}
+	if fi.Size() != t.params.ChallengeSize {
+		return nil, errors.New("the challenge file has the wrong size")
+	}
+
+	h, _ := blake2b.New512(nil)
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	t.ChallengeHash = h.Sum(nil)
+
+	t.head, err = readAccumulatorHead(f, t.params, false)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Parameters returns the size of the ceremony.
+func (t *Transcript) Parameters() *Parameters {
+	return t.params
+}
+
+// readAccumulatorHead reads the first points of the accumulator in f, which
+// must be a challenge file, or a response file if compressed is true.
+func readAccumulatorHead(f io.ReaderAt, params *Parameters, compressed bool) (*accumulatorHead, error) {
+	g1Size, g2Size := int64(bls12.G1UncompressedSize), int64(bls12.G2UncompressedSize)
+	if compressed {
+		g1Size, g2Size = bls12.G1CompressedSize, bls12.G2CompressedSize
+	}
+	tauG1 := int64(blake2b.Size)
+	tauG2 := tauG1 + int64(params.TauPowersG1)*g1Size
+	alphaTau := tauG2 + int64(params.TauPowers)*g2Size
+	betaTau := alphaTau + int64(params.TauPowers)*g1Size
+	betaG2 := betaTau + int64(params.TauPowers)*g1Size
+
+	read := func(off, size int64) io.Reader {
+		return io.NewSectionReader(f, off, size)
+	}
+	a := &accumulatorHead{}
+	g1, err := readG1Slice(read(tauG1, 2*g1Size), "TauG1", 2, compressed, false)
+	if err != nil {
+		return nil, err
+	}
+	copy(a.TauG1[:], g1)
+	g2, err := readG2Slice(read(tauG2, 2*g2Size), "TauG2", 2, compressed, false)
+	if err != nil {
+		return nil, err
+	}
+	copy(a.TauG2[:], g2)
+	g1, err = readG1Slice(read(alphaTau, g1Size), "AlphaTau", 1, compressed, false)
+	if err != nil {
+		return nil, err
+	}
+	a.AlphaTau = g1[0]
+	g1, err = readG1Slice(read(betaTau, g1Size), "BetaTau", 1, compressed, false)
+	if err != nil {
+		return nil, err
+	}
+	a.BetaTau = g1[0]
+	g2, err = readG2Slice(read(betaG2, g2Size), "BetaG2", 1, compressed, false)
+	if err != nil {
+		return nil, err
+	}
+	a.BetaG2 = g2[0]
+	return a, nil
+}
+
+func (a *accumulatorHead) Close() {
+	a.TauG2[0].Close()
+	a.TauG2[1].Close()
+	a.BetaG2.Close()
+}
+
+// Verify checks that the response file is a valid contribution on top of
+// the current challenge, and if so makes the challenge that follows it the
+// current one.
+//
+// The returned Challenge has all hashes, the Parameters and the PublicKey
+// set, but a nil Accumulator.
+func (t *Transcript) Verify(responseFile string) (*Challenge, error) {
+	params := t.params
+	f, err := os.Open(responseFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if fi.Size() != params.ResponseSize {
+		return nil, errors.New("the response file has the wrong size")
+	}
+
+	// The next challenge starts with the hash of the whole response, so we
+	// need to read it once before starting.
+	h, _ := blake2b.New512(nil)
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	c := &Challenge{
+		PreviousHash:  t.ChallengeHash,
+		ChallengeHash: make([]byte, blake2b.Size),
+		ResponseHash:  h.Sum(nil),
+		Parameters:    params,
+	}
+	if _, err := f.ReadAt(c.ChallengeHash, 0); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(c.ChallengeHash, t.ChallengeHash) {
+		return nil, errors.New("the response is not based on the previous challenge")
+	}
+
+	keyOffset := params.ResponseSize - PublicKeySize
+	c.PublicKey, err = ReadPublicKey(io.NewSectionReader(f, keyOffset, PublicKeySize), t.opts.CheckSubgroup)
+	if err != nil {
+		return nil, err
+	}
+	g2s, err := c.PublicKey.verify(t.ChallengeHash)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		for _, q := range g2s {
+			q.Close()
+		}
+	}()
+
+	head, err := readAccumulatorHead(f, params, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkUpdate(t.head, head, c.PublicKey, g2s); err != nil {
+		head.Close()
+		return nil, err
+	}
+
+	next, _ := blake2b.New512(nil)
+	next.Write(c.ResponseHash)
+	r := io.NewSectionReader(f, blake2b.Size, keyOffset-blake2b.Size)
+	if err := t.verifyPowers(r, next, head); err != nil {
+		head.Close()
+		return nil, err
+	}
+
+	t.head.Close()
+	t.head = head
+	t.ChallengeHash = next.Sum(nil)
+	t.Contributions++
+	return c, nil
+}
+
+// verifyPowers reads the compressed accumulator from r, writes it
+// uncompressed to next, and checks that all its vectors are sequences of
+// powers of tau like Verify, using the points in head as the ratios.
+func (t *Transcript) verifyPowers(r io.Reader, next io.Writer, head *accumulatorHead) error {
+	processes, checkSubgroup := t.opts.Processes, t.opts.CheckSubgroup
+	m := &bls12.MultiExp{Workers: processes}
+
+	tauG1 := newRatioG1(m)
+	if err := streamG1(r, next, "TauG1", t.params.TauPowersG1, checkSubgroup, processes, tauG1.add); err != nil {
+		return err
+	}
+	if !tauG1.check(head.TauG2[0], head.TauG2[1]) {
+		return errors.New("TauG1 is not a sequence of powers of tau")
+	}
+	tauG2 := newRatioG2(m)
+	defer tauG2.Close()
+	if err := streamG2(r, next, "TauG2", t.params.TauPowers, checkSubgroup, processes, tauG2.add); err != nil {
+		return err
+	}
+	if !tauG2.check(head.TauG1[0], head.TauG1[1]) {
+		return errors.New("TauG2 is not a sequence of powers of tau")
+	}
+	alphaTau := newRatioG1(m)
+	if err := streamG1(r, next, "AlphaTau", t.params.TauPowers, checkSubgroup, processes, alphaTau.add); err != nil {
+		return err
+	}
+	if !alphaTau.check(head.TauG2[0], head.TauG2[1]) {
+		return errors.New("AlphaTau is not a sequence of powers of tau")
+	}
+	betaTau := newRatioG1(m)
+	if err := streamG1(r, next, "BetaTau", t.params.TauPowers, checkSubgroup, processes, betaTau.add); err != nil {
+		return err
+	}
+	if !betaTau.check(head.TauG2[0], head.TauG2[1]) {
+		return errors.New("BetaTau is not a sequence of powers of tau")
+	}
+	return streamG2(r, next, "BetaG2", 1, checkSubgroup, processes, func(v []*bls12.EP2) {
+		for _, p := range v {
+			p.Close()
+		}
+	})
+}
+
+// streamG1 reads n compressed points in chunks, decodes them in parallel,
+// writes them uncompressed to next, and passes them to f in order.
+func streamG1(r io.Reader, next io.Writer, name string, n int, checkSubgroup bool, processes int, f func([]*bls12.EP)) error {
+	buf := make([]byte, transcriptChunk*bls12.G1CompressedSize)
+	out := make([]byte, transcriptChunk*bls12.G1UncompressedSize)
+	for a := 0; a < n; a += transcriptChunk {
+		k := n - a
+		if k > transcriptChunk {
+			k = transcriptChunk
+		}
+		in := buf[:k*bls12.G1CompressedSize]
+		if _, err := io.ReadFull(r, in); err != nil {
+			return err
+		}
+		points := make([]*bls12.EP, k)
+		errs := make([]error, k)
+		Parallelize(k, processes, func(start, end int) {
+			for i := start; i < end; i++ {
+				p, err := (&bls12.EP{}).DecodeCompressed(in[i*bls12.G1CompressedSize:][:bls12.G1CompressedSize])
+				if err != nil {
+					errs[i] = fmt.Errorf("invalid G1 point %s[%d]: %v", name, a+i, err)
+					return
+				}
+				if checkSubgroup && !p.IsInSubgroup() {
+					errs[i] = fmt.Errorf("invalid G1 point %s[%d]: not in the prime order subgroup", name, a+i)
+					return
+				}
+				copy(out[i*bls12.G1UncompressedSize:], p.EncodeUncompressed())
+				points[i] = p
+			}
+		})
+		if err := firstError(errs); err != nil {
+			return err
+		}
+		if _, err := next.Write(out[:k*bls12.G1UncompressedSize]); err != nil {
+			return err
+		}
+		f(points)
+	}
+	return nil
+}
+
+// streamG2 is like streamG1, but for G2 points, which f must close.
+func streamG2(r io.Reader, next io.Writer, name string, n int, checkSubgroup bool, processes int, f func([]*bls12.EP2)) error {
+	buf := make([]byte, transcriptChunk*bls12.G2CompressedSize)
+	out := make([]byte, transcriptChunk*bls12.G2UncompressedSize)
+	for a := 0; a < n; a += transcriptChunk {
+		k := n - a
+		if k > transcriptChunk {
+			k = transcriptChunk
+		}
+		in := buf[:k*bls12.G2CompressedSize]
+		if _, err := io.ReadFull(r, in); err != nil {
+			return err
+		}
+		points := make([]*bls12.EP2, k)
+		errs := make([]error, k)
+		Parallelize(k, processes, func(start, end int) {
+			for i := start; i < end; i++ {
+				p := bls12.NewEP2()
+				if _, err := p.DecodeCompressed(in[i*bls12.G2CompressedSize:][:bls12.G2CompressedSize]); err != nil {
+					errs[i] = fmt.Errorf("invalid G2 point %s[%d]: %v", name, a+i, err)
+					p.Close()
+					return
+				}
+				if checkSubgroup && !p.IsInSubgroup() {
+					errs[i] = fmt.Errorf("invalid G2 point %s[%d]: not in the prime order subgroup", name, a+i)
+					p.Close()
+					return
+				}
+				copy(out[i*bls12.G2UncompressedSize:], p.EncodeUncompressed())
+				points[i] = p
+			}
+		})
+		err := firstError(errs)
+		if err == nil {
+			_, err = next.Write(out[:k*bls12.G2UncompressedSize])
+		}
+		if err != nil {
+			for _, p := range points {
+				if p != nil {
+					p.Close()
+				}
+			}
+			return err
+		}
+		f(points)
+	}
+	return nil
+}
+
+func firstError(errs []error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ratioG1 computes the random linear combinations of SameRatioG1 over
+// consecutive chunks of a vector.
+type ratioG1 struct {
+	m           *bls12.MultiExp
+	last, s, sx *bls12.EP
+}
+
+func newRatioG1(m *bls12.MultiExp) *ratioG1 {
+	return &ratioG1{m: m, s: (&bls12.EP{}).SetZero(), sx: (&bls12.EP{}).SetZero()}
+}
+
+func (r *ratioG1) add(v []*bls12.EP) {
+	if r.last != nil {
+		v = append([]*bls12.EP{r.last}, v...)
+	}
+	r.last = v[len(v)-1]
+	if len(v) < 2 {
+		return
+	}
+	scalars := randomScalars(len(v) - 1)
+	r.s.Add(r.m.G1(v[:len(v)-1], scalars))
+	r.sx.Add(r.m.G1(v[1:], scalars))
+}
+
+// check is like SameRatioG1 on all the points passed to add.
+func (r *ratioG1) check(g2, g2x *bls12.EP2) bool {
+	return SameRatio(r.s, r.sx, g2, g2x)
+}
+
+// ratioG2 is like ratioG1, but for G2 vectors. It takes ownership of the
+// points passed to add.
+type ratioG2 struct {
+	m           *bls12.MultiExp
+	last, s, sx *bls12.EP2
+}
+
+func newRatioG2(m *bls12.MultiExp) *ratioG2 {
+	return &ratioG2{m: m, s: bls12.NewEP2().SetZero(), sx: bls12.NewEP2().SetZero()}
+}
+
+func (r *ratioG2) add(v []*bls12.EP2) {
+	if r.last != nil {
+		v = append([]*bls12.EP2{r.last}, v...)
+	}
+	defer func() {
+		for _, p := range v[:len(v)-1] {
+			p.Close()
+		}
+	}()
+	r.last = v[len(v)-1]
+	if len(v) < 2 {
+		return
+	}
+	scalars := randomScalars(len(v) - 1)
+	s, sx := r.m.G2(v[:len(v)-1], scalars), r.m.G2(v[1:], scalars)
+	r.s.Add(s)
+	r.sx.Add(sx)
+	s.Close()
+	sx.Close()
+}
+
+// check is like SameRatioG2 on all the points passed to add.
+func (r *ratioG2) check(g1, g1x *bls12.EP) bool {
+	return SameRatio(g1, g1x, r.s, r.sx)
+}
+
+func (r *ratioG2) Close() {
+	r.s.Close()
+	r.sx.Close()
+	if r.last != nil {
+		r.last.Close()
+	}
+}
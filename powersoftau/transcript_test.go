@@ -0,0 +1,122 @@
+package powersoftau
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+func TestTranscript(t *testing.T) {
+	defer func(n int) { transcriptChunk = n }(transcriptChunk)
+	transcriptChunk = 1000 // not a divisor of the vector lengths
+
+	dir, err := ioutil.TempDir("", "powersoftau")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := func(name string, i int) string {
+		return filepath.Join(dir, fmt.Sprintf("%s%d", name, i))
+	}
+
+	if err := WriteNewChallenge(file("challenge", 0), testParams); err != nil {
+		t.Fatal(err)
+	}
+	var responseHashes [][]byte
+	for i := 0; i < 3; i++ {
+		c, err := ComputeStream(file("challenge", i), file("response", i), &StreamOptions{
+			NextFile: file("challenge", i+1), Processes: 2,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		responseHashes = append(responseHashes, c.ResponseHash)
+	}
+
+	tr, err := NewTranscript(file("challenge", 0), &TranscriptOptions{CheckSubgroup: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(tr.ChallengeHash, NewChallengeHash(testParams)) {
+		t.Error("the initial challenge hash is not the new challenge hash")
+	}
+	if _, err := tr.Verify(file("response", 1)); err == nil {
+		t.Error("a response out of order was accepted")
+	}
+	for i := 0; i < 3; i++ {
+		c, err := tr.Verify(file("response", i))
+		if err != nil {
+			t.Fatalf("response %d: %v", i, err)
+		}
+		if !bytes.Equal(c.ResponseHash, responseHashes[i]) {
+			t.Errorf("response %d: wrong response hash", i)
+		}
+		next, err := ioutil.ReadFile(file("challenge", i+1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h := blake2b.Sum512(next); !bytes.Equal(tr.ChallengeHash, h[:]) {
+			t.Errorf("response %d: wrong next challenge hash", i)
+		}
+	}
+	if tr.Contributions != 3 {
+		t.Errorf("got %d contributions, expected 3", tr.Contributions)
+	}
+}
+
+func TestTranscriptInvalid(t *testing.T) {
+	defer func(n int) { transcriptChunk = n }(transcriptChunk)
+	transcriptChunk = 1000
+
+	dir, err := ioutil.TempDir("", "powersoftau")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	challengeFile := filepath.Join(dir, "challenge")
+	writeTestChallenge(t, challengeFile, testParams)
+
+	for _, tc := range []struct {
+		name, err string
+		tamper    func(a *Accumulator)
+	}{
+		{"TauG1", "TauG1 is not", func(a *Accumulator) { a.TauG1[2500].Double() }},
+		{"TauG2", "TauG2 is not", func(a *Accumulator) { a.TauG2[1500].Double() }},
+		{"AlphaTau", "AlphaTau is not", func(a *Accumulator) { a.AlphaTau[1999].Neg() }},
+		{"BetaTau", "BetaTau is not", func(a *Accumulator) { a.BetaTau[1000].Double() }},
+		{"BetaG2", "BetaG2 was not", func(a *Accumulator) { a.BetaG2.Double() }},
+		{"generator", "TauG1[0] is not", func(a *Accumulator) { a.TauG1[0].Double() }},
+	} {
+		c, err := ReadChallenge(challengeFile, nil, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Compute(2); err != nil {
+			t.Fatal(err)
+		}
+		tc.tamper(c.Accumulator)
+		responseFile := filepath.Join(dir, "response"+tc.name)
+		if err := WriteResponse(responseFile, c); err != nil {
+			t.Fatal(err)
+		}
+
+		tr, err := NewTranscript(challengeFile, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hash := tr.ChallengeHash
+		_, err = tr.Verify(responseFile)
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%s: got error %v, expected %q", tc.name, err, tc.err)
+		}
+		if !bytes.Equal(tr.ChallengeHash, hash) || tr.Contributions != 0 {
+			t.Errorf("%s: the transcript advanced after a failure", tc.name)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package powersoftau
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/FiloSottile/powersoftau/bls12"
+)
+
+// Verify checks that response, as read by ReadResponse, is a valid
+// contribution on top of the challenge c, using processes goroutines.
+func (c *Challenge) Verify(response *Challenge, processes int) error {
+	if !bytes.Equal(response.ChallengeHash, c.ChallengeHash) {
+		return errors.New("the response is not based on this challenge")
+	}
+	if response.Parameters.Power != c.Parameters.Power {
+		return errors.New("the response has a different size than the challenge")
+	}
+
+	before, after, key := c.Accumulator, response.Accumulator, response.PublicKey
+
+	g2s, err := key.verify(c.ChallengeHash)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, q := range g2s {
+			q.Close()
+		}
+	}()
+
+	if err := checkUpdate(before.head(), after.head(), key, g2s); err != nil {
+		return err
+	}
+
+	if !SameRatioG1(after.TauG1, after.TauG2[0], after.TauG2[1], processes) {
+		return errors.New("TauG1 is not a sequence of powers of tau")
+	}
+	if !SameRatioG2(after.TauG2, after.TauG1[0], after.TauG1[1], processes) {
+		return errors.New("TauG2 is not a sequence of powers of tau")
+	}
+	if !SameRatioG1(after.AlphaTau, after.TauG2[0], after.TauG2[1], processes) {
+		return errors.New("AlphaTau is not a sequence of powers of tau")
+	}
+	if !SameRatioG1(after.BetaTau, after.TauG2[0], after.TauG2[1], processes) {
+		return errors.New("BetaTau is not a sequence of powers of tau")
+	}
+
+	return nil
+}
+
+// accumulatorHead is the first points of an accumulator, which are all
+// that's needed to check that it was updated with a given public key.
+type accumulatorHead struct {
+	TauG1    [2]*bls12.EP
+	TauG2    [2]*bls12.EP2
+	AlphaTau *bls12.EP
+	BetaTau  *bls12.EP
+	BetaG2   *bls12.EP2
+}
+
+func (a *Accumulator) head() *accumulatorHead {
+	return &accumulatorHead{
+		TauG1:    [2]*bls12.EP{a.TauG1[0], a.TauG1[1]},
+		TauG2:    [2]*bls12.EP2{a.TauG2[0], a.TauG2[1]},
+		AlphaTau: a.AlphaTau[0],
+		BetaTau:  a.BetaTau[0],
+		BetaG2:   a.BetaG2,
+	}
+}
+
+// checkUpdate checks that after is before updated with the keypair of key,
+// given the G2 points returned by key.verify.
+func checkUpdate(before, after *accumulatorHead, key *PublicKey, g2s [3]*bls12.EP2) error {
+	tauG2s, alphaG2s, betaG2s := g2s[0], g2s[1], g2s[2]
+
+	for _, p := range []*bls12.EP{after.TauG1[1], after.AlphaTau, after.BetaTau} {
+		if p.IsZero() {
+			return errors.New("the accumulator contains an unexpected point at infinity")
+		}
+	}
+	for _, p := range []*bls12.EP2{after.TauG2[1], after.BetaG2} {
+		if p.IsZero() {
+			return errors.New("the accumulator contains an unexpected point at infinity")
+		}
+	}
+
+	g1 := (&bls12.EP{}).SetOne()
+	g2 := bls12.NewEP2().SetOne()
+	defer g2.Close()
+	if !after.TauG1[0].Equal(g1) {
+		return errors.New("TauG1[0] is not the G1 generator")
+	}
+	if !after.TauG2[0].Equal(g2) {
+		return errors.New("TauG2[0] is not the G2 generator")
+	}
+
+	if !SameRatio(before.TauG1[1], after.TauG1[1], tauG2s, key.Tau.SxG2x) {
+		return errors.New("TauG1 was not updated with the tau of the public key")
+	}
+	if !SameRatio(before.AlphaTau, after.AlphaTau, alphaG2s, key.Alpha.SxG2x) {
+		return errors.New("AlphaTau was not updated with the alpha of the public key")
+	}
+	if !SameRatio(before.BetaTau, after.BetaTau, betaG2s, key.Beta.SxG2x) {
+		return errors.New("BetaTau was not updated with the beta of the public key")
+	}
+	if !SameRatio(before.BetaTau, after.BetaTau, before.BetaG2, after.BetaG2) {
+		return errors.New("BetaG2 was not updated consistently with BetaTau")
+	}
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package powersoftau
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/FiloSottile/powersoftau/bls12"
+)
+
+func TestVerifyTampered(t *testing.T) {
+	dir, err := ioutil.TempDir("", "powersoftau")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	params, _ := NewParameters(3)
+	challengeFile := filepath.Join(dir, "challenge")
+	writeTestChallenge(t, challengeFile, params)
+	responseFile := filepath.Join(dir, "response")
+
+	c, err := ReadChallenge(challengeFile, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Compute(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteResponse(responseFile, c); err != nil {
+		t.Fatal(err)
+	}
+	ch, err := ReadChallenge(challengeFile, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := ReadResponse(responseFile, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ch.Verify(r, 2); err != nil {
+		t.Fatalf("valid response failed verification: %v", err)
+	}
+
+	addG1 := func(p **bls12.EP) {
+		*p = (*p).Copy().Add((&bls12.EP{}).SetOne())
+	}
+	addG2 := func(p **bls12.EP2) {
+		one := bls12.NewEP2().SetOne()
+		defer one.Close()
+		q := (*p).Copy().Add(one)
+		(*p).Close()
+		*p = q
+	}
+
+	tests := []struct {
+		name   string
+		tamper func(r *Challenge)
+		err    string
+	}{
+		{"TauG1[0]", func(r *Challenge) { addG1(&r.Accumulator.TauG1[0]) }, "TauG1[0] is not the G1 generator"},
+		{"TauG1[1]", func(r *Challenge) { addG1(&r.Accumulator.TauG1[1]) }, "TauG1 was not updated"},
+		{"TauG1[2]", func(r *Challenge) { addG1(&r.Accumulator.TauG1[2]) }, "TauG1 is not a sequence"},
+		{"TauG1 last", func(r *Challenge) {
+			addG1(&r.Accumulator.TauG1[params.TauPowersG1-1])
+		}, "TauG1 is not a sequence"},
+		{"TauG2[0]", func(r *Challenge) { addG2(&r.Accumulator.TauG2[0]) }, "TauG2[0] is not the G2 generator"},
+		{"TauG2[1]", func(r *Challenge) { addG2(&r.Accumulator.TauG2[1]) }, "TauG1 is not a sequence"},
+		{"TauG2[2]", func(r *Challenge) { addG2(&r.Accumulator.TauG2[2]) }, "TauG2 is not a sequence"},
+		{"AlphaTau[0]", func(r *Challenge) { addG1(&r.Accumulator.AlphaTau[0]) }, "AlphaTau was not updated"},
+		{"AlphaTau[1]", func(r *Challenge) { addG1(&r.Accumulator.AlphaTau[1]) }, "AlphaTau is not a sequence"},
+		{"BetaTau[0]", func(r *Challenge) { addG1(&r.Accumulator.BetaTau[0]) }, "BetaTau was not updated"},
+		{"BetaTau[1]", func(r *Challenge) { addG1(&r.Accumulator.BetaTau[1]) }, "BetaTau is not a sequence"},
+		{"BetaG2", func(r *Challenge) { addG2(&r.Accumulator.BetaG2) }, "BetaG2 was not updated"},
+		{"BetaG2 zero", func(r *Challenge) { r.Accumulator.BetaG2.SetZero() }, "point at infinity"},
+		{"tau proof", func(r *Challenge) { addG1(&r.PublicKey.Tau.Sx) }, "tau proof of knowledge is invalid"},
+		{"alpha proof", func(r *Challenge) { addG2(&r.PublicKey.Alpha.SxG2x) }, "alpha proof of knowledge is invalid"},
+		{"beta proof", func(r *Challenge) { r.PublicKey.Beta.S.SetZero() }, "beta proof of knowledge"},
+		{"other key", func(r *Challenge) {
+			// A valid proof of knowledge of a key that was not used.
+			r.PublicKey, _ = NewKeypair(r.ChallengeHash)
+		}, "TauG1 was not updated"},
+		{"other challenge key", func(r *Challenge) {
+			r.PublicKey, _ = NewKeypair(make([]byte, 64))
+		}, "tau proof of knowledge is invalid"},
+		{"challenge hash", func(r *Challenge) { r.ChallengeHash[0] ^= 1 }, "not based on this challenge"},
+	}
+	for _, tt := range tests {
+		r, err := ReadResponse(responseFile, nil, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tt.tamper(r)
+		if err := ch.Verify(r, 2); err == nil {
+			t.Errorf("%s: accepted a tampered response", tt.name)
+		} else if !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: got error %q, want %q", tt.name, err, tt.err)
+		}
+	}
+}